@@ -0,0 +1,395 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ==== Admin API ====
+// Svi zahtevi traže zaglavlje "Authorization: Bearer <WSPREF_ADMIN_TOKEN>".
+// Bez tokena u okruženju admin API je isključen.
+var adminToken string
+
+func initAdmin() {
+	adminToken = konfig.AdminToken
+	if adminToken == "" {
+		slog.Warn("admin-token nije zadat — /admin je isključen")
+	}
+}
+
+func adminOnly(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if adminToken == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			jsonError(w, r, http.StatusUnauthorized, tr("admin_token"))
+			return
+		}
+		h(w, r)
+	}
+}
+
+func registerAdmin() {
+	http.HandleFunc("GET /admin/rooms", adminOnly(handleAdminRooms))
+	http.HandleFunc("GET /admin/rooms/{id}", adminOnly(handleAdminRoom))
+	http.HandleFunc("POST /admin/rooms/{id}/kick", adminOnly(handleAdminKick))
+	http.HandleFunc("POST /admin/rooms/{id}/replace", adminOnly(handleAdminReplace))
+	http.HandleFunc("POST /admin/rooms/{id}/end-hand", adminOnly(handleAdminEndHand))
+	http.HandleFunc("POST /admin/rooms/{id}/close", adminOnly(handleAdminClose))
+	http.HandleFunc("POST /admin/notice", adminOnly(handleAdminNotice))
+	http.HandleFunc("GET /admin/claims", adminOnly(handleAdminClaims))
+}
+
+// nazivFaze opisuje gde je soba u toku podele; mora da se zove sa zaključanim r.mu
+func (r *Room) nazivFaze() string {
+	switch {
+	case len(r.players) < 3:
+		return fazaCekanje.String()
+	case r.pauza:
+		return "pauza"
+	case r.zahtev != nil:
+		return "zahtev"
+	}
+	return r.faza.String()
+}
+
+// opis je stanje sobe za admin API; mora da se zove sa zaključanim r.mu
+func (r *Room) opis(id string) map[string]any {
+	mesta := []map[string]any{}
+	for _, p := range r.players {
+		m := map[string]any{
+			"mesto":   p.id,
+			"ime":     p.ime(),
+			"povezan": p.povezan,
+			"refe":    p.refe,
+		}
+		if p.user != nil {
+			m["korisnik"] = p.user.ID
+		}
+		if p.zamena != 0 {
+			m["zamena"] = p.zamena
+		}
+		mesta = append(mesta, m)
+	}
+	sort.Slice(mesta, func(i, j int) bool { return mesta[i]["mesto"].(int) < mesta[j]["mesto"].(int) })
+	o := map[string]any{
+		"id":        id,
+		"faza":      r.nazivFaze(),
+		"pravila":   r.rules.Naziv,
+		"mesta":     mesta,
+		"gledalaca": len(r.gledaoci),
+		"podela":    r.podela(),
+		"trening":   r.trening,
+		"vezbanje":  r.vezbanje != nil,
+	}
+	if r.match != nil {
+		o["bule"] = r.match.bule
+	}
+	if r.turnir != nil {
+		o["turnir"] = r.turnir.t.ID
+	}
+	return o
+}
+
+// GET /admin/rooms
+func handleAdminRooms(w http.ResponseWriter, r *http.Request) {
+	mu.Lock()
+	defer mu.Unlock()
+	ids := make([]string, 0, len(rooms))
+	for id := range rooms {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	out := make([]map[string]any, 0, len(ids))
+	for _, id := range ids {
+		room := rooms[id]
+		room.mu.Lock()
+		out = append(out, room.opis(id))
+		room.mu.Unlock()
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// sobaZaAdmina vraća sobu iz putanje sa zaključanim mu i r.mu; pozivalac otključava oba
+func sobaZaAdmina(w http.ResponseWriter, r *http.Request) (*Room, string, bool) {
+	id := r.PathValue("id")
+	mu.Lock()
+	room := rooms[id]
+	if room == nil {
+		mu.Unlock()
+		jsonError(w, r, http.StatusNotFound, tr("nema_sobe", id))
+		return nil, id, false
+	}
+	room.mu.Lock()
+	return room, id, true
+}
+
+// GET /admin/rooms/{id}
+func handleAdminRoom(w http.ResponseWriter, r *http.Request) {
+	room, id, ok := sobaZaAdmina(w, r)
+	if !ok {
+		return
+	}
+	defer mu.Unlock()
+	defer room.mu.Unlock()
+	writeJSON(w, http.StatusOK, room.opis(id))
+}
+
+type adminMesto struct {
+	Mesto    int    `json:"mesto"`
+	Korisnik string `json:"korisnik"` // ime naloga koji preuzima mesto (samo za replace)
+}
+
+func citajMesto(w http.ResponseWriter, r *http.Request, room *Room) (*Player, adminMesto, bool) {
+	var zahtev adminMesto
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&zahtev); err != nil {
+		jsonError(w, r, http.StatusBadRequest, tr("neispravan_zahtev"))
+		return nil, zahtev, false
+	}
+	for _, p := range room.players {
+		if p.id == zahtev.Mesto {
+			return p, zahtev, true
+		}
+	}
+	jsonError(w, r, http.StatusNotFound, tr("prazno_mesto", zahtev.Mesto))
+	return nil, zahtev, false
+}
+
+// izbaci zatvara vezu igrača; mesto sa kartama ostaje za stolom i čeka zamenu
+func izbaci(room *Room, p *Player, zamena int) {
+	if p.user != nil {
+		p.izbacen = p.user.ID
+	}
+	p.zamena = zamena
+	p.povezan = false
+	room.posalji(p, map[string]any{
+		"type":    "obavestenje",
+		"message": tr("admin_udaljen"),
+	})
+	p.conn.Close()
+	room.broadcast(map[string]any{
+		"type":    "info",
+		"message": tr("udaljen", p.ime()),
+	})
+}
+
+// POST /admin/rooms/{id}/kick {"mesto": 1}
+func handleAdminKick(w http.ResponseWriter, r *http.Request) {
+	room, id, ok := sobaZaAdmina(w, r)
+	if !ok {
+		return
+	}
+	defer mu.Unlock()
+	defer room.mu.Unlock()
+	p, _, ok := citajMesto(w, r, room)
+	if !ok {
+		return
+	}
+	izbaci(room, p, zamenaBiloKo)
+	p.log().Info("administrator izbacio igrača")
+	writeJSON(w, http.StatusOK, room.opis(id))
+}
+
+// POST /admin/rooms/{id}/replace {"mesto": 1, "korisnik": "pera"}
+func handleAdminReplace(w http.ResponseWriter, r *http.Request) {
+	room, id, ok := sobaZaAdmina(w, r)
+	if !ok {
+		return
+	}
+	defer mu.Unlock()
+	defer room.mu.Unlock()
+	p, zahtev, ok := citajMesto(w, r, room)
+	if !ok {
+		return
+	}
+	u := korisnici.poImenu(zahtev.Korisnik)
+	if u == nil {
+		jsonError(w, r, http.StatusNotFound, tr("nema_naloga", zahtev.Korisnik))
+		return
+	}
+	if room.sedi(u) {
+		jsonError(w, r, http.StatusConflict, tr("vec_sedi", u.Ime))
+		return
+	}
+	izbaci(room, p, u.ID)
+	p.log().Info("administrator rezervisao mesto", "zamena", u.Ime)
+	writeJSON(w, http.StatusOK, room.opis(id))
+}
+
+// POST /admin/rooms/{id}/end-hand prekida ruku bez obračuna i deli novu
+func handleAdminEndHand(w http.ResponseWriter, r *http.Request) {
+	room, id, ok := sobaZaAdmina(w, r)
+	if !ok {
+		return
+	}
+	defer mu.Unlock()
+	defer room.mu.Unlock()
+	if len(room.players) < 3 || (room.match != nil && room.match.gotov) {
+		jsonError(w, r, http.StatusConflict, tr("ne_igra_se_ruka"))
+		return
+	}
+	switch {
+	case room.turnir != nil:
+		// turnirska podela se ponavlja sa istim kartama i bez novog rezultata
+		room.turnir.ponoviPodelu()
+	case room.igra == nil:
+		// pripremiIgru već pomera deljenje kad igra počne
+		room.startIndex = (room.startIndex + 1) % 3
+	}
+	room.igra = nil
+	room.broadcast(map[string]any{
+		"type":    "obavestenje",
+		"message": tr("admin_prekid_ruke"),
+	})
+	dealCards(room)
+	room.javiStanje()
+	room.log().Info("administrator prekinuo ruku")
+	writeJSON(w, http.StatusOK, room.opis(id))
+}
+
+// POST /admin/rooms/{id}/close zatvara sve veze i briše sobu; turnirski sto se računa kao završen
+func handleAdminClose(w http.ResponseWriter, r *http.Request) {
+	room, id, ok := sobaZaAdmina(w, r)
+	if !ok {
+		return
+	}
+	defer mu.Unlock()
+	defer room.mu.Unlock()
+	room.broadcast(map[string]any{
+		"type":    "obavestenje",
+		"message": tr("admin_zatvorio"),
+	})
+	if room.turnir != nil {
+		// sto se računa kao završen, inače kolo nikad ne bi došlo do kraja
+		room.turnir.zavrsiSto(room)
+	}
+	for _, p := range append(append([]*Player{}, room.players...), room.gledaoci...) {
+		p.povezan = false
+		p.conn.Close()
+	}
+	delete(rooms, id)
+	room.log().Info("administrator zatvorio sto")
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+// POST /admin/notice {"poruka": "..."} šalje obaveštenje svim vezama
+func handleAdminNotice(w http.ResponseWriter, r *http.Request) {
+	var zahtev struct {
+		Poruka string `json:"poruka"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&zahtev); err != nil || strings.TrimSpace(zahtev.Poruka) == "" {
+		jsonError(w, r, http.StatusBadRequest, tr("neispravan_zahtev"))
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	veza := 0
+	for _, room := range rooms {
+		room.mu.Lock()
+		room.broadcast(map[string]any{
+			"type":    "obavestenje",
+			"message": zahtev.Poruka,
+		})
+		veza += len(room.players) + len(room.gledaoci)
+		room.mu.Unlock()
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"poslato": veza})
+}
+
+// GET /admin/claims vraća ruke završene nepoštenim zahtevom deklaranta
+func handleAdminClaims(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, zapisi.sporniZahtevi())
+}
+
+// ==== Zamena igrača ====
+const zamenaBiloKo = -1 // mesto može da preuzme bilo ko osim izbačenog
+
+// preuzmiMesto seda korisnika na upražnjeno mesto u sobi (/ws?soba=room1): svoje
+// mesto posle prekida veze, mesto rezervisano za njega ili mesto otvoreno za zamenu.
+// Vraća igrača čije stanje (karte, refe) novi korisnik nastavlja.
+func preuzmiMesto(conn *veza, u *User, roomID string) (*Player, error) {
+	mu.Lock()
+	room := rooms[roomID]
+	mu.Unlock()
+	if room == nil {
+		return nil, tr("nema_sobe", roomID)
+	}
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	var mesto *Player
+	for _, p := range room.players {
+		if !p.povezan && p.user != nil && p.user.ID == u.ID && p.izbacen != u.ID {
+			mesto = p
+			break
+		}
+	}
+	if mesto == nil && room.sedi(u) {
+		// ko već ima mesto za stolom ne može da zauzme i drugo
+		return nil, tr("vec_sedis")
+	}
+	if mesto == nil {
+		for _, p := range room.players {
+			if !p.povezan && (p.zamena == u.ID || (p.zamena == zamenaBiloKo && p.izbacen != u.ID)) {
+				mesto = p
+				break
+			}
+		}
+	}
+	if mesto == nil {
+		return nil, tr("nema_mesta")
+	}
+	if mesto.user == nil || mesto.user.ID != u.ID {
+		mesto.izbacen = 0
+	} else {
+		metrika.ponovnaPovezivanja.Add(1)
+	}
+	mesto.conn = conn
+	mesto.user = u
+	mesto.name = u.Ime
+	mesto.povezan = true
+	mesto.zamena = 0
+	mesto.uSobu(roomID)
+
+	room.posalji(mesto, map[string]any{
+		"type":    "you_are",
+		"id":      mesto.id,
+		"ime":     mesto.ime(),
+		"pravila": room.rules,
+	})
+	room.posalji(mesto, map[string]any{
+		"type":  "your_cards",
+		"cards": mesto.cards,
+	})
+	room.broadcast(map[string]any{
+		"type":    "igraci",
+		"message": tr("preuzima_mesto", u.Ime, mesto.id),
+		"imena":   room.imena(),
+		"rejting": room.rejtingStola(),
+	})
+	if room.vezbanje != nil && !room.vezbanje.pocelo {
+		room.pocniVezbanje()
+	}
+	if room.pauza && !gasiSe.Load() && sviPovezani(room) {
+		// sto vraćen posle restarta nastavlja meč kad se svi vrate
+		room.pauza = false
+		msg := room.match.stanje()
+		msg["type"] = "nastavak_meca"
+		msg["message"] = tr("nastavak_meca")
+		room.broadcast(msg)
+		dealCards(room)
+	}
+	room.javiStanje()
+	return mesto, nil
+}
+
+func sviPovezani(r *Room) bool {
+	for _, p := range r.players {
+		if !p.povezan {
+			return false
+		}
+	}
+	return len(r.players) == 3
+}
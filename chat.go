@@ -0,0 +1,191 @@
+package main
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ==== Chat ====
+// Igrači za stolom pišu u zajednički kanal koji vide i gledaoci. Gledaoci imaju
+// svoj kanal koji igrači ne vide dok traje ruka, da ne bi mogli da im odaju karte.
+const (
+	maxDuzinaChata = 200
+	chatLimit      = 5   // poruka po igraču u konfig.ChatProzor
+	chatIstorija   = 100 // poslednjih poruka koje soba pamti
+)
+
+type ChatPoruka struct {
+	Vreme    time.Time `json:"vreme"`
+	Igrac    int       `json:"igrac"`
+	Ime      string    `json:"ime"`
+	Tekst    string    `json:"tekst"`
+	Kod      string    `json:"kod,omitempty"` // brza poruka
+	Gledalac bool      `json:"gledalac,omitempty"`
+}
+
+// BrzePoruke rade i kad su pravila sobe isključila slobodan chat
+var BrzePoruke = map[string]map[string]string{
+	"bravo":   {"sr": "Dobro odigrano", "en": "Well played"},
+	"zurim":   {"sr": "Žurim", "en": "I'm in a hurry"},
+	"hvala":   {"sr": "Hvala", "en": "Thanks"},
+	"zdravo":  {"sr": "Zdravo", "en": "Hello"},
+	"izvini":  {"sr": "Izvini", "en": "Sorry"},
+	"srecno":  {"sr": "Srećno", "en": "Good luck"},
+	"razmisl": {"sr": "Razmišljam…", "en": "Thinking…"},
+}
+
+// dozvoljenChat primenjuje ograničenje broja poruka po igraču
+func (p *Player) dozvoljenChat() bool {
+	sada := time.Now()
+	skorasnje := p.chatVremena[:0]
+	for _, t := range p.chatVremena {
+		if sada.Sub(t) < konfig.ChatProzor {
+			skorasnje = append(skorasnje, t)
+		}
+	}
+	p.chatVremena = skorasnje
+	if len(p.chatVremena) >= chatLimit {
+		return false
+	}
+	p.chatVremena = append(p.chatVremena, sada)
+	return true
+}
+
+// posaljiChat obrađuje slobodnu poruku igrača ili gledaoca
+func (r *Room) posaljiChat(p *Player, tekst string) {
+	tekst = strings.TrimSpace(tekst)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case tekst == "":
+		return
+	case utf8.RuneCountInString(tekst) > maxDuzinaChata:
+		r.greska(p, tr("chat_dugacka", maxDuzinaChata))
+		return
+	case !p.gledalac && !r.rules.SlobodanChat:
+		r.greska(p, tr("chat_iskljucen"))
+		return
+	case !p.dozvoljenChat():
+		r.greska(p, tr("chat_previse"))
+		return
+	}
+	r.objaviChat(ChatPoruka{Vreme: time.Now(), Igrac: p.id, Ime: p.ime(), Tekst: tekst, Gledalac: p.gledalac})
+}
+
+// posaljiBrzuPoruku šalje poruku iz kataloga; tekst u zapisu je na srpskom, a
+// "message" stiže na jeziku svake veze
+func (r *Room) posaljiBrzuPoruku(p *Player, kod string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prevodi, ok := BrzePoruke[kod]
+	if !ok {
+		r.greska(p, tr("chat_nepoznata"))
+		return
+	}
+	if !p.dozvoljenChat() {
+		r.greska(p, tr("chat_previse"))
+		return
+	}
+	r.objaviChat(ChatPoruka{Vreme: time.Now(), Igrac: p.id, Ime: p.ime(), Tekst: prevodi["sr"], Kod: kod, Gledalac: p.gledalac})
+}
+
+// objaviChat pamti poruku i šalje je; zove se sa zaključanim r.mu
+func (r *Room) objaviChat(c ChatPoruka) {
+	var tekst any = c.Tekst
+	if c.Kod != "" {
+		tekst = tr("brza_" + c.Kod)
+	}
+	msg := map[string]any{
+		"type":    "chat",
+		"message": tr("chat", c.Ime, tekst),
+		"chat":    c,
+	}
+	if c.Gledalac {
+		r.chatGledalaca = append(r.chatGledalaca, c)
+		if len(r.chatGledalaca) > chatIstorija {
+			r.chatGledalaca = r.chatGledalaca[1:]
+		}
+	} else {
+		r.chat = append(r.chat, c)
+		if len(r.chat) > chatIstorija {
+			r.chat = r.chat[1:]
+		}
+	}
+
+	if c.Gledalac && r.deliSeRuka() {
+		// dok traje ruka igrači ne vide kanal gledalaca, ni kad gledaju sa druge veze
+		for _, g := range r.gledaoci {
+			if g.user != nil && r.sedi(g.user) {
+				continue
+			}
+			r.posalji(g, msg)
+		}
+		return
+	}
+	r.broadcast(msg)
+}
+
+// deliSeRuka javlja da li su karte podeljene a ruka još nije završena;
+// zove se sa zaključanim r.mu
+func (r *Room) deliSeRuka() bool {
+	switch r.nazivFaze() {
+	case "cekanje", "kraj_meca", "pauza":
+		return false
+	}
+	return true
+}
+
+// istorijaChata šalje igraču ili gledaocu poruke koje je propustio pre ulaska
+func (r *Room) istorijaChata(p *Player) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	poruke := append([]ChatPoruka{}, r.chat...)
+	if p.gledalac {
+		poruke = append(poruke, r.chatGledalaca...)
+	}
+	r.posalji(p, map[string]any{
+		"type":   "chat_istorija",
+		"poruke": poruke,
+	})
+}
+
+// gledaj dodaje gledaoca u sobu; gledalac dobija sve javne poruke stola, ali ne i karte
+func gledaj(p *Player, roomID string) error {
+	mu.Lock()
+	room := rooms[roomID]
+	if room == nil {
+		mu.Unlock()
+		return tr("nema_sobe", roomID)
+	}
+	room.mu.Lock()
+	sedi := p.user != nil && room.sedi(p.user)
+	room.mu.Unlock()
+	if sedi {
+		// igrač ne sme da čita kanal gledalaca o svom stolu
+		mu.Unlock()
+		return tr("gledaj_sedis")
+	}
+	p.gledalac = true
+	p.id = -1
+	p.uSobu(roomID)
+	room.mu.Lock()
+	room.gledaoci = append(room.gledaoci, p)
+	p.podela.Store(int32(room.dealCount))
+	room.posalji(p, map[string]any{
+		"type":     "you_are",
+		"id":       p.id,
+		"ime":      p.ime(),
+		"gledalac": true,
+		"pravila":  room.rules,
+		"imena":    room.imena(),
+	})
+	room.mu.Unlock()
+	mu.Unlock()
+
+	room.istorijaChata(p)
+	room.mu.Lock()
+	room.posaljiStanje(p)
+	room.mu.Unlock()
+	return nil
+}
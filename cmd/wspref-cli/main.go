@@ -0,0 +1,612 @@
+// wspref-cli — terminalni klijent za preferans (igra preko /ws protokola)
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+
+	"multiplayer-game/preferans"
+)
+
+// ==== Boje za terminal ====
+const (
+	reset  = "\033[0m"
+	crvena = "\033[31m"
+	bold   = "\033[1m"
+	siva   = "\033[90m"
+	zuta   = "\033[33m"
+)
+
+// Faze u kojima klijent može nešto da pošalje
+const (
+	fazaCekanje     = ""
+	fazaLicitacija  = "licitacija"
+	fazaPotvrda     = "potvrda"
+	fazaStil        = "stil"
+	fazaOdbacivanje = "odbacivanje"
+	fazaPracenje    = "pracenje"
+	fazaKontra      = "kontra"
+	fazaIgra        = "igra"
+	fazaRevans      = "revans"
+)
+
+var rankOrder = map[string]int{
+	"7": 1, "8": 2, "9": 3, "10": 4, "J": 5, "Q": 6, "K": 7, "A": 8,
+}
+
+var suitOrder = map[rune]int{
+	'♠': 1, '♦': 2, '♥': 3, '♣': 4,
+}
+
+// Kratka imena boja koja se mogu kucati umesto simbola
+var suitNames = map[string]string{
+	"pik": "♠", "karo": "♦", "herc": "♥", "tref": "♣",
+	"s": "♠", "d": "♦", "h": "♥", "c": "♣",
+}
+
+// prijavi se prijavljuje (ili registruje) preko HTTP API-ja istog servera i
+// vraća zaglavlje sa kolačićem sesije za otvaranje /ws veze
+func prijavi(addr, ime, lozinka string, registruj bool) (http.Header, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "wss":
+		u.Scheme = "https"
+	default:
+		u.Scheme = "http"
+	}
+	u.Path = "/api/login"
+	if registruj {
+		u.Path = "/api/register"
+	}
+	u.RawQuery = ""
+	telo, _ := json.Marshal(map[string]string{"ime": ime, "lozinka": lozinka})
+	res, err := http.Post(u.String(), "application/json", bytes.NewReader(telo))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(res.Body).Decode(&e)
+		return nil, fmt.Errorf("%s: %s", res.Status, e.Error)
+	}
+	header := http.Header{}
+	for _, c := range res.Cookies() {
+		header.Add("Cookie", c.Name+"="+c.Value)
+	}
+	return header, nil
+}
+
+// ==== Strukture ====
+type bacena struct {
+	player int
+	card   string
+}
+
+type stanje struct {
+	id       int
+	cards    []string
+	talon    []string
+	trick    []bacena
+	ponude   []int    // ponude koje server sada prima od ovog igrača
+	aduti    []string // aduti ponuđeni uz potvrdu ili izbor aduta
+	faza     string
+	naPotezu int
+	poruke   []string
+	bule     []int
+	boje     bool
+}
+
+func main() {
+	addr := flag.String("addr", "ws://localhost:8080/ws", "adresa servera (ws:// ili wss://)")
+	bezBoja := flag.Bool("bez-boja", false, "isključi ANSI boje")
+	ime := flag.String("ime", os.Getenv("WSPREF_IME"), "korisničko ime")
+	lozinka := flag.String("lozinka", os.Getenv("WSPREF_LOZINKA"), "lozinka")
+	registruj := flag.Bool("registruj", false, "napravi nov nalog pre ulaska")
+	flag.Parse()
+
+	if *ime == "" || *lozinka == "" {
+		log.Fatal("Potrebni su -ime i -lozinka (ili WSPREF_IME i WSPREF_LOZINKA)")
+	}
+	header, err := prijavi(*addr, *ime, *lozinka, *registruj)
+	if err != nil {
+		log.Fatalf("Prijava nije uspela: %v", err)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(*addr, header)
+	if err != nil {
+		log.Fatalf("Ne mogu da se povežem na %s: %v", *addr, err)
+	}
+	defer conn.Close()
+
+	s := &stanje{id: -1, naPotezu: -1, boje: !*bezBoja}
+
+	serverMsgs := make(chan map[string]any)
+	go func() {
+		defer close(serverMsgs)
+		for {
+			var m map[string]any
+			if err := conn.ReadJSON(&m); err != nil {
+				log.Println("Read error:", err)
+				return
+			}
+			serverMsgs <- m
+		}
+	}()
+
+	linije := make(chan string)
+	go func() {
+		defer close(linije)
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			linije <- sc.Text()
+		}
+	}()
+
+	s.render()
+	for {
+		select {
+		case m, ok := <-serverMsgs:
+			if !ok {
+				fmt.Println("Veza sa serverom je prekinuta.")
+				return
+			}
+			s.primi(m)
+			s.render()
+		case l, ok := <-linije:
+			if !ok {
+				return
+			}
+			msg, err := s.komanda(strings.TrimSpace(l))
+			if err != nil {
+				s.dodajPoruku(s.oboji(crvena, err.Error()))
+			} else if msg != nil {
+				if err := conn.WriteJSON(msg); err != nil {
+					log.Println("Write error:", err)
+					return
+				}
+			}
+			s.render()
+		}
+	}
+}
+
+// fazeAkcija: akcija iz state poruke -> faza u kojoj klijent prima komande
+var fazeAkcija = []struct{ akcija, faza string }{
+	{"bid", fazaLicitacija}, {"pass", fazaLicitacija}, {"potvrdi_igru", fazaPotvrda},
+	{"stil_odabran", fazaStil}, {"odbaci_karte", fazaOdbacivanje}, {"prati", fazaPracenje},
+	{"kontra_odgovor", fazaKontra}, {"baci_kartu", fazaIgra}, {"revans", fazaRevans},
+}
+
+// primi ažurira stanje prema poruci sa servera
+func (s *stanje) primi(m map[string]any) {
+	tip, _ := m["type"].(string)
+	if txt, ok := m["message"].(string); ok && txt != "" {
+		if tip == "error" {
+			txt = s.oboji(crvena, txt)
+		}
+		s.dodajPoruku(txt)
+	}
+	switch tip {
+	case "state":
+		// state stiže posle svake obrađene poruke, i posle odbijenog poteza, pa
+		// se faza i opcije uvek vraćaju na ono što server stvarno čeka
+		if _, ok := m["cards"]; ok {
+			s.cards = stringLista(m["cards"])
+			sortCards(s.cards)
+		}
+		s.ponude = intLista(m["ponude"])
+		s.aduti = stringLista(m["aduti"])
+		akcije := stringLista(m["akcije"])
+		s.faza = fazaCekanje
+		for _, fa := range fazeAkcija {
+			if slices.Contains(akcije, fa.akcija) {
+				s.faza = fa.faza
+				break
+			}
+		}
+	case "you_are":
+		s.id = intPolje(m, "id")
+		s.dodajPoruku(fmt.Sprintf("Ti si igrač %d.", s.id))
+	case "your_cards":
+		s.cards = stringLista(m["cards"])
+		sortCards(s.cards)
+	case "auction_start":
+		s.trick = nil
+		s.talon = nil
+		s.naPotezu = intPolje(m, "player")
+		if s.naPotezu == s.id {
+			s.faza = fazaLicitacija
+		}
+	case "your_turn":
+		s.ponude = intLista(m["ponude"])
+		s.faza = fazaLicitacija
+	case "potvrdi_igru":
+		s.aduti = stringLista(m["aduti"])
+		s.faza = fazaPotvrda
+	case "biraj_stil":
+		s.talon = stringLista(m["cards"])
+		s.aduti = stringLista(m["aduti"])
+		s.faza = fazaStil
+	case "talon_info":
+		s.talon = stringLista(m["talon"])
+	case "discard_talon":
+		s.cards = stringLista(m["cards"])
+		sortCards(s.cards)
+		s.faza = fazaOdbacivanje
+	case "prati_prompt":
+		s.faza = fazaPracenje
+	case "kontra_prompt":
+		s.faza = fazaKontra
+	case "start_game":
+		s.trick = nil
+		s.faza = fazaCekanje
+	case "turn":
+		s.naPotezu = intPolje(m, "player")
+		if s.naPotezu == s.id {
+			s.faza = fazaIgra
+		} else {
+			s.faza = fazaCekanje
+		}
+	case "karta_bacena":
+		card, _ := m["card"].(string)
+		if len(s.trick) == 3 {
+			s.trick = nil
+		}
+		s.trick = append(s.trick, bacena{player: intPolje(m, "player"), card: card})
+	case "obracun", "novi_mec":
+		s.bule = intLista(m["bule"])
+		s.faza = fazaCekanje
+	case "kraj_meca":
+		s.bule = intLista(m["bule"])
+		s.dodajPoruku(fmt.Sprintf("Konačni saldo: %v", intLista(m["saldo"])))
+	case "revans_prompt":
+		s.faza = fazaRevans
+	case "chat_istorija":
+		if poruke, ok := m["poruke"].([]any); ok {
+			for _, x := range poruke {
+				if c, ok := x.(map[string]any); ok {
+					s.dodajPoruku(fmt.Sprintf("%v: %v", c["ime"], c["tekst"]))
+				}
+			}
+		}
+	}
+}
+
+// komanda pretvara ono što je korisnik otkucao u poruku za server
+func (s *stanje) komanda(l string) (map[string]any, error) {
+	if l == "" {
+		return nil, nil
+	}
+	polja := strings.Fields(l)
+	cmd := strings.ToLower(polja[0])
+	args := polja[1:]
+
+	switch cmd {
+	case "pomoc", "pomoć", "help", "?":
+		s.dodajPoruku(pomoc)
+		return nil, nil
+	case "kraj", "quit", "q":
+		os.Exit(0)
+	case "chat", "c":
+		// chat je dozvoljen u svakoj fazi
+		return map[string]any{"type": "chat", "tekst": strings.TrimSpace(l[len(polja[0]):])}, nil
+	case "brzo":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("upotreba: brzo <kod> (bravo, zurim, hvala, zdravo, izvini, srecno, razmisl)")
+		}
+		return map[string]any{"type": "brza_poruka", "kod": args[0]}, nil
+	}
+
+	// faza se ovde samo privremeno gasi; ako server odbije potez, state je vraća
+	switch s.faza {
+	case fazaLicitacija:
+		for _, v := range s.ponude {
+			if cmd == preferans.NazivPonude(v) || cmd == "pass" && v == preferans.Pas {
+				s.faza = fazaCekanje
+				return map[string]any{"type": "bid", "value": v}, nil
+			}
+		}
+		return nil, fmt.Errorf("nepoznata ponuda %q (dozvoljeno: %s)", cmd, s.naziviPonuda())
+
+	case fazaPotvrda:
+		if len(s.aduti) == 0 {
+			// adut se ne bira uz potvrdu, pa se vrednost ne šalje
+			s.faza = fazaCekanje
+			return map[string]any{"type": "potvrdi_igru"}, nil
+		}
+		boja, err := s.adut(cmd)
+		if err != nil {
+			return nil, err
+		}
+		s.faza = fazaCekanje
+		return map[string]any{"type": "potvrdi_igru", "value": boja}, nil
+
+	case fazaStil:
+		boja, err := s.adut(cmd)
+		if err != nil {
+			return nil, err
+		}
+		s.faza = fazaCekanje
+		return map[string]any{"type": "stil_odabran", "stil": boja}, nil
+
+	case fazaOdbacivanje:
+		if cmd == "odbaci" {
+			polja = args
+		}
+		if len(polja) != 2 {
+			return nil, fmt.Errorf("moraš odbaciti tačno 2 karte (npr. \"odbaci 1 5\")")
+		}
+		karte := []string{}
+		for _, a := range polja {
+			c, err := s.karta(a)
+			if err != nil {
+				return nil, err
+			}
+			karte = append(karte, c)
+		}
+		if karte[0] == karte[1] {
+			return nil, fmt.Errorf("izabrao si istu kartu dva puta")
+		}
+		s.faza = fazaCekanje
+		return map[string]any{"type": "odbaci_karte", "karte": karte}, nil
+
+	case fazaPracenje:
+		switch cmd {
+		case "prati", "da", "p":
+			s.faza = fazaCekanje
+			return map[string]any{"type": "prati", "prati": true}, nil
+		case "dalje", "ne", "n":
+			s.faza = fazaCekanje
+			return map[string]any{"type": "prati", "prati": false}, nil
+		}
+		return nil, fmt.Errorf("odgovori sa \"prati\" ili \"dalje\"")
+
+	case fazaKontra:
+		switch cmd {
+		case "kontra", "da", "k":
+			s.faza = fazaCekanje
+			return map[string]any{"type": "kontra_odgovor", "kontra": true}, nil
+		case "dalje", "ne", "n":
+			s.faza = fazaCekanje
+			return map[string]any{"type": "kontra_odgovor", "kontra": false}, nil
+		}
+		return nil, fmt.Errorf("odgovori sa \"kontra\" ili \"dalje\"")
+
+	case fazaRevans:
+		switch cmd {
+		case "da", "revans":
+			s.faza = fazaCekanje
+			return map[string]any{"type": "revans", "prihvatam": true}, nil
+		case "ne":
+			s.faza = fazaCekanje
+			return map[string]any{"type": "revans", "prihvatam": false}, nil
+		}
+		return nil, fmt.Errorf("odgovori sa \"da\" ili \"ne\"")
+
+	case fazaIgra:
+		if cmd == "baci" {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("upotreba: baci <karta ili redni broj>")
+			}
+			cmd = args[0]
+		}
+		c, err := s.karta(cmd)
+		if err != nil {
+			return nil, err
+		}
+		s.faza = fazaCekanje
+		return map[string]any{"type": "baci_kartu", "card": c}, nil
+	}
+	return nil, fmt.Errorf("nisi na potezu")
+}
+
+// adut prihvata simbol ili ime boje, ali samo među ponuđenim adutima
+func (s *stanje) adut(a string) (string, error) {
+	if boja, ok := suitNames[a]; ok {
+		a = boja
+	}
+	if !slices.Contains(s.aduti, a) {
+		return "", fmt.Errorf("izaberi adut: %s", strings.Join(s.aduti, " | "))
+	}
+	return a, nil
+}
+
+// naziviPonuda su ponude koje server sada prima, onako kako se kucaju
+func (s *stanje) naziviPonuda() string {
+	nazivi := []string{}
+	for _, v := range s.ponude {
+		nazivi = append(nazivi, preferans.NazivPonude(v))
+	}
+	return strings.Join(nazivi, " | ")
+}
+
+// karta prihvata redni broj iz ruke (1..n) ili zapis poput "10♥" i "Kh"
+func (s *stanje) karta(a string) (string, error) {
+	if n, err := strconv.Atoi(a); err == nil {
+		if n < 1 || n > len(s.cards) {
+			return "", fmt.Errorf("nema karte pod brojem %d", n)
+		}
+		return s.cards[n-1], nil
+	}
+	up := strings.ToUpper(a)
+	for ime, boja := range suitNames {
+		if len(ime) == 1 && strings.HasSuffix(strings.ToLower(up), ime) {
+			up = strings.TrimSuffix(up, strings.ToUpper(ime)) + boja
+			break
+		}
+	}
+	for _, c := range s.cards {
+		if c == up {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("nemaš kartu %q", a)
+}
+
+func (s *stanje) dodajPoruku(txt string) {
+	s.poruke = append(s.poruke, txt)
+	if len(s.poruke) > 8 {
+		s.poruke = s.poruke[len(s.poruke)-8:]
+	}
+}
+
+// render iscrtava ceo ekran iz trenutnog stanja
+func (s *stanje) render() {
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	b.WriteString(s.oboji(bold, "PREFERANS"))
+	if s.id >= 0 {
+		fmt.Fprintf(&b, "  — igrač %d", s.id)
+	}
+	if len(s.bule) > 0 {
+		fmt.Fprintf(&b, "  bule: %v", s.bule)
+	}
+	b.WriteString("\n\n")
+
+	for _, p := range s.poruke {
+		b.WriteString(s.oboji(siva, "  "+p) + "\n")
+	}
+	b.WriteString("\n")
+
+	if len(s.talon) > 0 {
+		b.WriteString("Talon: " + s.prikaziKarte(s.talon, false) + "\n")
+	}
+	if len(s.trick) > 0 {
+		b.WriteString("Štih:  ")
+		for _, t := range s.trick {
+			fmt.Fprintf(&b, "%s(%d) ", s.obojiKartu(t.card), t.player)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\nRuka:  " + s.prikaziKarte(s.cards, true) + "\n\n")
+
+	switch s.faza {
+	case fazaLicitacija:
+		b.WriteString(s.oboji(zuta, "Licitacija: "+s.naziviPonuda()) + "\n")
+	case fazaPotvrda:
+		if len(s.aduti) == 0 {
+			b.WriteString(s.oboji(zuta, "Potvrdi igru: potvrdi") + "\n")
+		} else {
+			b.WriteString(s.oboji(zuta, "Potvrdi igru i izaberi adut: "+strings.Join(s.aduti, " | ")+" (ili pik, karo, herc, tref)") + "\n")
+		}
+	case fazaStil:
+		b.WriteString(s.oboji(zuta, "Izaberi adut: "+strings.Join(s.aduti, " | ")+" (ili pik, karo, herc, tref)") + "\n")
+	case fazaOdbacivanje:
+		b.WriteString(s.oboji(zuta, "Odbaci dve karte (npr. \"odbaci 3 7\"):") + "\n")
+	case fazaPracenje:
+		b.WriteString(s.oboji(zuta, "prati | dalje") + "\n")
+	case fazaKontra:
+		b.WriteString(s.oboji(zuta, "kontra | dalje") + "\n")
+	case fazaIgra:
+		b.WriteString(s.oboji(zuta, "Na potezu si — baci kartu (redni broj ili npr. \"Kh\"):") + "\n")
+	case fazaRevans:
+		b.WriteString(s.oboji(zuta, "Revanš? da | ne") + "\n")
+	default:
+		b.WriteString(s.oboji(siva, "Čekaj...  (\"pomoc\" za komande)") + "\n")
+	}
+	b.WriteString("> ")
+	fmt.Print(b.String())
+}
+
+func (s *stanje) prikaziKarte(cards []string, saBrojevima bool) string {
+	delovi := []string{}
+	for i, c := range cards {
+		if saBrojevima {
+			delovi = append(delovi, fmt.Sprintf("%s%d:%s %s", siva, i+1, reset, s.obojiKartu(c)))
+		} else {
+			delovi = append(delovi, s.obojiKartu(c))
+		}
+	}
+	out := strings.Join(delovi, "  ")
+	if !s.boje {
+		out = strings.ReplaceAll(strings.ReplaceAll(out, siva, ""), reset, "")
+	}
+	return out
+}
+
+func (s *stanje) obojiKartu(c string) string {
+	_, suit := parseCard(c)
+	if suit == '♥' || suit == '♦' {
+		return s.oboji(crvena+bold, c)
+	}
+	return s.oboji(bold, c)
+}
+
+func (s *stanje) oboji(boja, txt string) string {
+	if !s.boje {
+		return txt
+	}
+	return boja + txt + reset
+}
+
+const pomoc = `Komande: pas | 2..7 | igra | betl | sans | potvrdi | pik, karo, herc, tref | prati | kontra | dalje | odbaci <a> <b> | baci <karta> | chat <tekst> | brzo <kod> | kraj`
+
+func sortCards(cards []string) {
+	sort.Slice(cards, func(i, j int) bool {
+		r1, s1 := parseCard(cards[i])
+		r2, s2 := parseCard(cards[j])
+		if suitOrder[s1] != suitOrder[s2] {
+			return suitOrder[s1] < suitOrder[s2]
+		}
+		return rankOrder[r1] < rankOrder[r2]
+	})
+}
+
+func parseCard(card string) (rank string, suit rune) {
+	runes := []rune(card)
+	if len(runes) == 3 {
+		return string(runes[0:2]), runes[2]
+	}
+	if len(runes) < 2 {
+		return card, 0
+	}
+	return string(runes[0]), runes[1]
+}
+
+func intPolje(m map[string]any, key string) int {
+	if v, ok := m[key].(float64); ok {
+		return int(v)
+	}
+	return -1
+}
+
+func intLista(v any) []int {
+	lista, _ := v.([]any)
+	out := []int{}
+	for _, x := range lista {
+		if f, ok := x.(float64); ok {
+			out = append(out, int(f))
+		}
+	}
+	return out
+}
+
+func stringLista(v any) []string {
+	lista, _ := v.([]any)
+	out := []string{}
+	for _, x := range lista {
+		if s, ok := x.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
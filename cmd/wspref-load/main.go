@@ -0,0 +1,415 @@
+// wspref-load — alat za opterećenje servera: otvara N stolova po 3 veze i igra nasumične partije.
+// Sve veze dolaze sa jedne adrese, pa server treba pokrenuti sa većim granicama, npr.
+// -veza-po-ip 1000 -ip-poruka-u-sekundi 100000.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ==== Merenja ====
+type metrike struct {
+	mu        sync.Mutex
+	latencije map[string][]time.Duration // po tipu poslate poruke
+	primljene map[string]int             // po tipu primljene poruke
+	greske    map[string]int             // po uzroku
+	poslato   int64
+	zavrseno  int64 // stolovi koji su odigrali ruku do kraja
+	neuspesno int64
+	pocetak   time.Time
+}
+
+func (m *metrike) latencija(tip string, d time.Duration) {
+	m.mu.Lock()
+	m.latencije[tip] = append(m.latencije[tip], d)
+	m.mu.Unlock()
+}
+
+func (m *metrike) primljena(tip string) {
+	m.mu.Lock()
+	m.primljene[tip]++
+	m.mu.Unlock()
+}
+
+func (m *metrike) greska(uzrok string) {
+	m.mu.Lock()
+	m.greske[uzrok]++
+	m.mu.Unlock()
+}
+
+// ==== Bot ====
+type bot struct {
+	conn    *websocket.Conn
+	rnd     *rand.Rand
+	met     *metrike
+	timeout time.Duration
+
+	id      int
+	poslato map[string]time.Time // tip poruke -> vreme slanja, čeka se odgovor
+	zadnje  string               // tip poslednje poslate poruke
+}
+
+func main() {
+	addr := flag.String("addr", "ws://localhost:8080/ws", "adresa servera")
+	stolovi := flag.Int("n", 10, "broj stolova koji igraju istovremeno (svaki ima 3 veze)")
+	trajanje := flag.Duration("trajanje", 30*time.Second, "koliko dugo traje test")
+	timeout := flag.Duration("timeout", 5*time.Second, "najduže čekanje na poruku servera")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seme za nasumične poteze")
+	prefiks := flag.String("nalozi", "loadbot", "prefiks imena naloga koje alat pravi za botove")
+	lozinka := flag.String("lozinka", "loadbot-lozinka", "lozinka naloga botova")
+	flag.Parse()
+
+	// svaki sto ima svoja tri naloga, jer server ne pušta isti nalog dvaput za sto
+	sesije := make([]http.Header, 3**stolovi)
+	for i := range sesije {
+		h, err := prijavi(*addr, fmt.Sprintf("%s%d", *prefiks, i), *lozinka)
+		if err != nil {
+			log.Fatalf("Prijava bota %d: %v", i, err)
+		}
+		sesije[i] = h
+	}
+
+	met := &metrike{
+		latencije: map[string][]time.Duration{},
+		primljene: map[string]int{},
+		greske:    map[string]int{},
+		pocetak:   time.Now(),
+	}
+
+	kraj := time.Now().Add(*trajanje)
+	var seedovi int64 = *seed
+	var sedanje sync.Mutex // da bi server stavio tri veze istog stola u istu sobu
+	var wg sync.WaitGroup
+	for i := 0; i < *stolovi; i++ {
+		wg.Add(1)
+		nalozi := sesije[3*i : 3*i+3]
+		go func() {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(atomic.AddInt64(&seedovi, 1)))
+			for time.Now().Before(kraj) {
+				if odigrajSto(*addr, nalozi, &sedanje, rnd, met, *timeout) {
+					atomic.AddInt64(&met.zavrseno, 1)
+				} else {
+					atomic.AddInt64(&met.neuspesno, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	izvestaj(met)
+}
+
+// odigrajSto otvara tri veze, igra jednu ruku i zatvara veze. Vraća true ako je ruka završena.
+func odigrajSto(addr string, nalozi []http.Header, sedanje *sync.Mutex, rnd *rand.Rand, met *metrike, timeout time.Duration) bool {
+	botovi := []*bot{}
+	defer func() {
+		for _, b := range botovi {
+			b.conn.Close()
+		}
+	}()
+
+	sedanje.Lock()
+	for i := 0; i < 3; i++ {
+		conn, _, err := websocket.DefaultDialer.Dial(addr, nalozi[i])
+		if err != nil {
+			sedanje.Unlock()
+			met.greska("dial")
+			return false
+		}
+		b := &bot{
+			conn:    conn,
+			rnd:     rand.New(rand.NewSource(rnd.Int63())),
+			met:     met,
+			timeout: timeout,
+			id:      -1,
+			poslato: map[string]time.Time{},
+		}
+		botovi = append(botovi, b)
+		// sačekaj you_are pre nego što se poveže sledeći igrač
+		if err := b.sledeca(); err != nil {
+			sedanje.Unlock()
+			return false
+		}
+	}
+	sedanje.Unlock()
+
+	rezultati := make(chan bool, 3)
+	for _, b := range botovi {
+		go func(b *bot) { rezultati <- b.igraj() }(b)
+	}
+	ok := true
+	for range botovi {
+		if !<-rezultati {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// prijavi prijavljuje bota, a ako nalog ne postoji pravi ga
+func prijavi(addr, ime, lozinka string) (http.Header, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "wss" {
+		u.Scheme = "https"
+	} else {
+		u.Scheme = "http"
+	}
+	u.RawQuery = ""
+	telo, _ := json.Marshal(map[string]string{"ime": ime, "lozinka": lozinka})
+	for _, putanja := range []string{"/api/login", "/api/register"} {
+		u.Path = putanja
+		res, err := http.Post(u.String(), "application/json", bytes.NewReader(telo))
+		if err != nil {
+			return nil, err
+		}
+		res.Body.Close()
+		if res.StatusCode/100 != 2 {
+			continue
+		}
+		header := http.Header{}
+		for _, c := range res.Cookies() {
+			header.Add("Cookie", c.Name+"="+c.Value)
+		}
+		return header, nil
+	}
+	return nil, fmt.Errorf("%s: ni prijava ni registracija nisu uspele", ime)
+}
+
+// sledeca čita jednu poruku sa servera i na nju odgovara
+func (b *bot) sledeca() error {
+	b.conn.SetReadDeadline(time.Now().Add(b.timeout))
+	var m map[string]any
+	if err := b.conn.ReadJSON(&m); err != nil {
+		if ne, ok := err.(interface{ Timeout() bool }); ok && ne.Timeout() {
+			if b.zadnje == "" {
+				b.met.greska("timeout pre prvog poteza")
+			} else {
+				b.met.greska("timeout posle " + b.zadnje)
+			}
+		} else {
+			b.met.greska("read")
+		}
+		return err
+	}
+	tip, _ := m["type"].(string)
+	b.met.primljena(tip)
+	// prva poruka posle slanja se računa kao odgovor
+	for poslat, t := range b.poslato {
+		b.met.latencija(poslat, time.Since(t))
+		delete(b.poslato, poslat)
+	}
+	if tip == "error" {
+		// server je odbio potez; posle greške stiže state, pa bot bira ponovo
+		b.met.greska("server error posle " + b.zadnje)
+	}
+	return b.odgovori(tip, m)
+}
+
+// igraj vrti petlju dok se ruka ne završi ili dok server ne prestane da odgovara
+func (b *bot) igraj() bool {
+	for {
+		if err := b.sledeca(); err != nil {
+			return err == errKraj
+		}
+	}
+}
+
+var errKraj = fmt.Errorf("kraj ruke")
+
+// odgovori reaguje na poruku servera. Bot ne zna pravila: odluke donosi iz
+// poruke state, birajući nasumično među opcijama koje server nudi (ponude,
+// aduti, broj, odgovori, legalne). State stiže posle svake obrađene poruke, pa
+// i posle odbijenog poteza.
+func (b *bot) odgovori(tip string, m map[string]any) error {
+	switch tip {
+	case "you_are":
+		b.id = intPolje(m, "id")
+	case "state":
+		return b.odluci(m)
+	case "obracun":
+		return errKraj
+	}
+	return nil
+}
+
+// odluci šalje potez ako state traži odluku od ovog bota
+func (b *bot) odluci(m map[string]any) error {
+	akcije := stringLista(m["akcije"])
+	ima := func(a string) bool { return slices.Contains(akcije, a) }
+	switch {
+	case ima("bid") || ima("pass"):
+		ponude := intLista(m["ponude"])
+		// pas je najčešći potez u pravoj licitaciji
+		if len(ponude) == 0 || b.rnd.Intn(2) == 0 {
+			return b.posalji(map[string]any{"type": "pass"})
+		}
+		return b.posalji(map[string]any{"type": "bid", "value": ponude[b.rnd.Intn(len(ponude))]})
+	case ima("potvrdi_igru"):
+		// adut se šalje samo kad je ponuđen uz potvrdu
+		if aduti := stringLista(m["aduti"]); len(aduti) > 0 {
+			return b.posalji(map[string]any{"type": "potvrdi_igru", "value": aduti[b.rnd.Intn(len(aduti))]})
+		}
+		return b.posalji(map[string]any{"type": "potvrdi_igru"})
+	case ima("stil_odabran"):
+		aduti := stringLista(m["aduti"])
+		return b.posalji(map[string]any{"type": "stil_odabran", "stil": aduti[b.rnd.Intn(len(aduti))]})
+	case ima("odbaci_karte"):
+		karte := stringLista(m["cards"])
+		perm := b.rnd.Perm(len(karte))[:intPolje(m, "broj")]
+		skart := []string{}
+		for _, i := range perm {
+			skart = append(skart, karte[i])
+		}
+		return b.posalji(map[string]any{"type": "odbaci_karte", "karte": skart})
+	case ima("prati"):
+		// prati se češće nego što se kaže dalje
+		return b.posalji(map[string]any{"type": "prati", "prati": b.odgovor(m, true, 5)})
+	case ima("kontra_odgovor"):
+		return b.posalji(map[string]any{"type": "kontra_odgovor", "kontra": b.odgovor(m, false, 5)})
+	case ima("claim_odgovor"):
+		return b.posalji(map[string]any{"type": "claim_odgovor", "prihvatam": b.odgovor(m, true, 2)})
+	case ima("baci_kartu"):
+		legalne := stringLista(m["legalne"])
+		return b.posalji(map[string]any{"type": "baci_kartu", "card": legalne[b.rnd.Intn(len(legalne))]})
+	}
+	return nil
+}
+
+// odgovor bira jedan od ponuđenih odgovora; cesto se bira kad god je ponuđen,
+// osim u jednom od n slučajeva
+func (b *bot) odgovor(m map[string]any, cesto bool, n int) bool {
+	odgovori := boolLista(m["odgovori"])
+	if slices.Contains(odgovori, cesto) && (len(odgovori) == 1 || b.rnd.Intn(n) != 0) {
+		return cesto
+	}
+	return !cesto
+}
+
+func (b *bot) posalji(msg map[string]any) error {
+	tip := msg["type"].(string)
+	b.poslato[tip] = time.Now()
+	b.zadnje = tip
+	atomic.AddInt64(&b.met.poslato, 1)
+	if err := b.conn.WriteJSON(msg); err != nil {
+		b.met.greska("write")
+		return err
+	}
+	return nil
+}
+
+func intPolje(m map[string]any, key string) int {
+	if v, ok := m[key].(float64); ok {
+		return int(v)
+	}
+	return -1
+}
+
+func intLista(v any) []int {
+	lista, _ := v.([]any)
+	out := []int{}
+	for _, x := range lista {
+		if f, ok := x.(float64); ok {
+			out = append(out, int(f))
+		}
+	}
+	return out
+}
+
+func boolLista(v any) []bool {
+	lista, _ := v.([]any)
+	out := []bool{}
+	for _, x := range lista {
+		if b, ok := x.(bool); ok {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+func stringLista(v any) []string {
+	lista, _ := v.([]any)
+	out := []string{}
+	for _, x := range lista {
+		if s, ok := x.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ==== Izveštaj ====
+func izvestaj(m *metrike) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	proteklo := time.Since(m.pocetak).Seconds()
+	w := os.Stdout
+
+	fmt.Fprintf(w, "Trajanje: %.1fs\n", proteklo)
+	fmt.Fprintf(w, "Stolova završeno: %d (%.2f/s), neuspešno: %d\n",
+		m.zavrseno, float64(m.zavrseno)/proteklo, m.neuspesno)
+	fmt.Fprintf(w, "Poslato poruka: %d\n\n", m.poslato)
+
+	fmt.Fprintf(w, "%-16s %8s %10s %10s %10s %10s\n", "poslato", "broj", "p50", "p90", "p99", "max")
+	for _, tip := range sortiraniKljucevi(m.latencije) {
+		l := m.latencije[tip]
+		sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+		fmt.Fprintf(w, "%-16s %8d %10s %10s %10s %10s\n", tip, len(l),
+			percentil(l, 50), percentil(l, 90), percentil(l, 99), l[len(l)-1])
+	}
+
+	fmt.Fprintf(w, "\n%-16s %8s\n", "primljeno", "broj")
+	for _, tip := range sortiraniKljucevi(m.primljene) {
+		fmt.Fprintf(w, "%-16s %8d\n", tip, m.primljene[tip])
+	}
+
+	ukupnoGresaka := 0
+	for _, n := range m.greske {
+		ukupnoGresaka += n
+	}
+	stopa := 0.0
+	if m.poslato > 0 {
+		stopa = 100 * float64(ukupnoGresaka) / float64(m.poslato)
+	}
+	fmt.Fprintf(w, "\nGreške: %d (%.2f%% od poslatih poruka)\n", ukupnoGresaka, stopa)
+	for _, uzrok := range sortiraniKljucevi(m.greske) {
+		fmt.Fprintf(w, "  %-28s %d\n", uzrok, m.greske[uzrok])
+	}
+	if ukupnoGresaka > 0 && m.zavrseno == 0 {
+		log.Println("Nijedan sto nije završio ruku — proveri da li server radi.")
+	}
+}
+
+func percentil(l []time.Duration, p int) time.Duration {
+	if len(l) == 0 {
+		return 0
+	}
+	i := (len(l) - 1) * p / 100
+	return l[i].Round(time.Microsecond)
+}
+
+func sortiraniKljucevi[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
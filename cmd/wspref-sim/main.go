@@ -0,0 +1,356 @@
+// wspref-sim — simulator bez mreže: deli ruke kroz pravila igre i skuplja statistiku
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"multiplayer-game/preferans"
+)
+
+// ==== Statistika ====
+type brojac struct {
+	Broj  int `json:"broj"`
+	Uspeh int `json:"uspeh"`
+	Poeni int `json:"poeni"`
+}
+
+type statistika struct {
+	Podela      int                `json:"podela"`
+	SviPas      int                `json:"svi_pas"`
+	Nevazece    int                `json:"nevazece"`      // igra od 2 bez kontre, kad je to po pravilima
+	Ugovori     map[string]*brojac `json:"ugovori"`       // uspeh = deklarant prošao
+	NikoNePrati map[string]int     `json:"niko_ne_prati"` // po ugovoru
+	Talon       brojac             `json:"talon"`         // uspeh = talon popravio procenu ruke
+	Kontra      map[string]*brojac `json:"kontra"`        // po nivou; uspeh = deklarant prošao
+	Odluke      map[string]*brojac `json:"odluke"`        // poeni = zbir poena onog ko je odlučio
+}
+
+func novaStatistika() *statistika {
+	return &statistika{
+		Ugovori:     map[string]*brojac{},
+		NikoNePrati: map[string]int{},
+		Kontra:      map[string]*brojac{},
+		Odluke:      map[string]*brojac{},
+	}
+}
+
+func (s *statistika) dodaj(m map[string]*brojac, kljuc string, uspeh bool, poeni int) {
+	b, ok := m[kljuc]
+	if !ok {
+		b = &brojac{}
+		m[kljuc] = b
+	}
+	b.Broj++
+	if uspeh {
+		b.Uspeh++
+	}
+	b.Poeni += poeni
+}
+
+func (s *statistika) spoji(o *statistika) {
+	s.Podela += o.Podela
+	s.SviPas += o.SviPas
+	s.Nevazece += o.Nevazece
+	s.Talon.Broj += o.Talon.Broj
+	s.Talon.Uspeh += o.Talon.Uspeh
+	for k, v := range o.NikoNePrati {
+		s.NikoNePrati[k] += v
+	}
+	for _, par := range []struct{ dst, src map[string]*brojac }{
+		{s.Ugovori, o.Ugovori}, {s.Kontra, o.Kontra}, {s.Odluke, o.Odluke},
+	} {
+		for k, v := range par.src {
+			b, ok := par.dst[k]
+			if !ok {
+				b = &brojac{}
+				par.dst[k] = b
+			}
+			b.Broj += v.Broj
+			b.Uspeh += v.Uspeh
+			b.Poeni += v.Poeni
+		}
+	}
+}
+
+// poeni svode ishod podele na jedan broj za igrača: supe minus deset supa po buli
+func poeni(o preferans.Obracun, igrac int) int {
+	return o.Supe[igrac] - 10*o.Bule[igrac]
+}
+
+// odluka pamti ko je šta odlučio u podeli da bi joj se na kraju pripisali poeni
+type odluka struct {
+	igrac int
+	naziv string
+}
+
+func main() {
+	n := flag.Int("n", 100000, "broj podela")
+	seed := flag.Int64("seed", 1, "seme generatora slučajnih brojeva")
+	izbor := flag.String("strategije", "jednostavna,jednostavna,jednostavna", "strategije za tri igrača, odvojene zarezom")
+	radnika := flag.Int("radnika", runtime.NumCPU(), "broj paralelnih radnika")
+	kaoJSON := flag.Bool("json", false, "ispiši statistiku kao JSON")
+	nazivPravila := flag.String("pravila", preferans.DefaultRules, "kućna pravila ("+strings.Join(preferans.NaziviPravila(), ", ")+")")
+	flag.Parse()
+
+	pravila, err := preferans.RulesZa(*nazivPravila)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	imena := strings.Split(*izbor, ",")
+	if len(imena) == 1 {
+		imena = []string{imena[0], imena[0], imena[0]}
+	}
+	if len(imena) != 3 {
+		log.Fatalf("-strategije mora imati jednu ili tri vrednosti, dobijeno %d", len(imena))
+	}
+	for _, ime := range imena {
+		if _, ok := strategije[ime]; !ok {
+			log.Fatalf("nepoznata strategija %q (dostupne: %s)", ime, strings.Join(dostupne(), ", "))
+		}
+	}
+
+	ukupno := novaStatistika()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < *radnika; w++ {
+		od := *n * w / *radnika
+		do := *n * (w + 1) / *radnika
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(*seed + int64(w)))
+			var igraci [3]Strategija
+			for i, ime := range imena {
+				igraci[i] = strategije[ime](rnd)
+			}
+			st := novaStatistika()
+			for i := od; i < do; i++ {
+				simulirajPodelu(pravila, igraci, i%3, rnd, st)
+			}
+			mu.Lock()
+			ukupno.spoji(st)
+			mu.Unlock()
+		}(w)
+	}
+	wg.Wait()
+
+	if *kaoJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(ukupno)
+		return
+	}
+	ispisi(ukupno, imena, pravila)
+}
+
+func dostupne() []string {
+	out := []string{}
+	for ime := range strategije {
+		out = append(out, ime)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// simulirajPodelu prolazi kroz istu sekvencu kao server: licitacija, talon i štil,
+// praćenje i kontra, pa deset štihova i obračun
+func simulirajPodelu(pravila preferans.Rules, igraci [3]Strategija, prvi int, rnd *rand.Rand, st *statistika) {
+	st.Podela++
+	ruke, talon := preferans.Podeli(preferans.ShuffleCards(preferans.Deck, rnd))
+	odluke := []odluka{}
+
+	// 1. Licitacija
+	l := preferans.NovaLicitacija(prvi, pravila)
+	for !l.Gotova() {
+		ja := l.NaPotezu
+		dozvoljene := l.Dozvoljene(ja)
+		ponuda := igraci[ja].Licitiraj(Pogled{Ja: ja, Ruka: ruke[ja], Ponuda: l.Najvisa, Deklarant: l.Deklarant, Dozvoljene: dozvoljene})
+		if l.Ponudi(ja, ponuda) != nil {
+			ponuda = preferans.Pas
+			l.Ponudi(ja, ponuda)
+		}
+		odluke = append(odluke, odluka{ja, "licitacija " + preferans.NazivPonude(ponuda)})
+	}
+	if l.SviPas() {
+		st.SviPas++
+		return
+	}
+	d, ponuda := l.Deklarant, l.Najvisa
+	ugovor := preferans.NazivPonude(ponuda)
+
+	// 2. Talon i izbor štila
+	var adut rune
+	if preferans.SaTalonom(ponuda) {
+		pre := najboljaProcena(ruke[d])
+		sa := append(append([]string{}, ruke[d]...), talon...)
+		preferans.SortCards(sa)
+		a, odbaci := igraci[d].Stil(Pogled{Ja: d, Ruka: sa, Ponuda: ponuda, Deklarant: d})
+		nova, ok := preferans.Ukloni(sa, odbaci...)
+		if !ok || len(nova) != 10 {
+			nova = sa[:10]
+		}
+		ruke[d], adut = nova, a
+		st.Talon.Broj++
+		if procena(ruke[d], adut) > pre {
+			st.Talon.Uspeh++
+		}
+	} else if preferans.BiraAdut(ponuda) {
+		adut, _ = igraci[d].Stil(Pogled{Ja: d, Ruka: ruke[d], Ponuda: ponuda, Deklarant: d})
+	}
+	if !preferans.BiraAdut(ponuda) {
+		adut = 0
+	}
+
+	// 3. Praćenje i kontra
+	var pratili [3]bool
+	pratili[d] = true
+	brojPratilaca := 0
+	for k := 1; k <= 2; k++ {
+		ja := (d + k) % 3
+		pratili[ja] = igraci[ja].Prati(Pogled{Ja: ja, Ruka: ruke[ja], Ponuda: ponuda, Adut: adut, Deklarant: d})
+		if pratili[ja] {
+			brojPratilaca++
+			odluke = append(odluke, odluka{ja, "prati"})
+		} else {
+			odluke = append(odluke, odluka{ja, "ne prati"})
+		}
+	}
+	kontra, kontraBy := 0, -1
+	if brojPratilaca > 0 {
+		for k := 1; k <= 2 && kontra == 0 && pravila.SmeKontru(kontra); k++ {
+			ja := (d + k) % 3
+			if pratili[ja] && igraci[ja].Kontra(Pogled{Ja: ja, Ruka: ruke[ja], Ponuda: ponuda, Adut: adut, Deklarant: d}) {
+				kontra, kontraBy = 1, ja
+				odluke = append(odluke, odluka{ja, "kontra"})
+			}
+		}
+		// na kontru deklarant može rekontru, a na nju onaj ko je dao kontru subkontru
+		for kontra > 0 && pravila.SmeKontru(kontra) {
+			ja := d
+			if kontra == 2 {
+				ja = kontraBy
+			}
+			if !igraci[ja].Kontra(Pogled{Ja: ja, Ruka: ruke[ja], Ponuda: ponuda, Adut: adut, Deklarant: d, Nivo: kontra}) {
+				break
+			}
+			kontra++
+			odluke = append(odluke, odluka{ja, nivoKontre(kontra)})
+		}
+	}
+
+	if pravila.NevazecaIgra(ponuda, kontra) {
+		st.Nevazece++
+		return
+	}
+
+	// 4. Igra — prvi baca deklarant
+	var stihovi [3]int
+	if brojPratilaca > 0 {
+		o := preferans.NovoOdigravanje(ruke, adut, d)
+		for !o.Gotovo() {
+			ja := o.NaPotezu
+			legalne := o.Legalne()
+			karta := igraci[ja].Baci(Pogled{Ja: ja, Ruka: o.Ruke[ja], Ponuda: ponuda, Adut: adut, Deklarant: d, Nivo: kontra, Stih: o.Stih, Legalne: legalne})
+			if _, err := o.Baci(ja, karta); err != nil {
+				o.Baci(ja, legalne[0])
+			}
+		}
+		stihovi = o.Stihovi
+	} else {
+		st.NikoNePrati[ugovor]++
+	}
+
+	// 5. Obračun
+	// podele se simuliraju nezavisno, pa niko nema otvoren refe
+	ob := pravila.Obracunaj(ponuda, d, pratili, stihovi, kontra, false)
+	st.dodaj(st.Ugovori, ugovor, ob.Prosao, poeni(ob, d))
+	if brojPratilaca > 0 {
+		st.dodaj(st.Kontra, nivoKontre(kontra), ob.Prosao, poeni(ob, d))
+	}
+	for _, od := range odluke {
+		st.dodaj(st.Odluke, od.naziv, false, poeni(ob, od.igrac))
+	}
+}
+
+func najboljaProcena(ruka []string) float64 {
+	_, p := najboljiAdut(ruka)
+	return p
+}
+
+func nivoKontre(k int) string {
+	if k == 0 {
+		return "bez kontre"
+	}
+	return []string{"kontra", "rekontra", "subkontra"}[k-1]
+}
+
+// ==== Ispis ====
+func ispisi(s *statistika, imena []string, pravila preferans.Rules) {
+	fmt.Printf("Strategije: %s, pravila: %s\n", strings.Join(imena, ", "), pravila.Naziv)
+	fmt.Printf("Podela: %d, svi pas: %d (%.1f%%), nevažeće igre od 2: %d\n\n", s.Podela, s.SviPas, procenat(s.SviPas, s.Podela), s.Nevazece)
+
+	fmt.Printf("%-8s %10s %10s %12s %14s\n", "ugovor", "igrano", "prolaz", "niko ne prati", "poeni deklar.")
+	redosled := []int{2, 3, 4, 5, 6, 7, preferans.Igra, preferans.Betl, preferans.Sans}
+	for _, v := range redosled {
+		ime := preferans.NazivPonude(v)
+		b := s.Ugovori[ime]
+		if b == nil {
+			continue
+		}
+		fmt.Printf("%-8s %10d %9.1f%% %12d %14.2f\n", ime, b.Broj, procenat(b.Uspeh, b.Broj), s.NikoNePrati[ime], prosek(b.Poeni, b.Broj))
+	}
+
+	fmt.Printf("\nTalon popravio ruku: %d od %d (%.1f%%)\n", s.Talon.Uspeh, s.Talon.Broj, procenat(s.Talon.Uspeh, s.Talon.Broj))
+
+	fmt.Printf("\n%-12s %10s %10s %10s\n", "kontra", "broj", "udeo", "prolaz")
+	ukupnoIgranih := 0
+	for _, b := range s.Kontra {
+		ukupnoIgranih += b.Broj
+	}
+	for k := 0; k <= preferans.MaxKontra; k++ {
+		b := s.Kontra[nivoKontre(k)]
+		if b == nil {
+			continue
+		}
+		fmt.Printf("%-12s %10d %9.1f%% %9.1f%%\n", nivoKontre(k), b.Broj, procenat(b.Broj, ukupnoIgranih), procenat(b.Uspeh, b.Broj))
+	}
+
+	fmt.Printf("\n%-20s %10s %14s\n", "odluka", "broj", "očekivani poeni")
+	for _, ime := range sortiraniKljucevi(s.Odluke) {
+		b := s.Odluke[ime]
+		fmt.Printf("%-20s %10d %14.2f\n", ime, b.Broj, prosek(b.Poeni, b.Broj))
+	}
+}
+
+func procenat(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return 100 * float64(a) / float64(b)
+}
+
+func prosek(zbir, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(zbir) / float64(n)
+}
+
+func sortiraniKljucevi(m map[string]*brojac) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+
+	"multiplayer-game/preferans"
+)
+
+// Pogled je ono što igrač vidi u trenutku odluke
+type Pogled struct {
+	Ja         int
+	Ruka       []string // u izboru štila ruka ima 12 karata (sa talonom)
+	Ponuda     int      // najviša ponuda u licitaciji, posle toga ugovor
+	Adut       rune
+	Deklarant  int
+	Nivo       int // trenutni nivo kontre
+	Stih       []string
+	Legalne    []string
+	Dozvoljene []int
+}
+
+// Strategija donosi sve odluke jednog igrača. Implementacija ne sme da čuva
+// stanje između podela jer je svaki radnik pravi za sebe.
+type Strategija interface {
+	Licitiraj(p Pogled) int
+	// Stil bira adut i, kad je uzet talon, dve karte za odbacivanje
+	Stil(p Pogled) (adut rune, odbaci []string)
+	Prati(p Pogled) bool
+	Kontra(p Pogled) bool
+	Baci(p Pogled) string
+}
+
+// strategije su sve strategije koje se mogu izabrati flagom -strategije
+var strategije = map[string]func(rnd *rand.Rand) Strategija{
+	"nasumicna":   func(rnd *rand.Rand) Strategija { return &nasumicna{rnd: rnd} },
+	"jednostavna": func(rnd *rand.Rand) Strategija { return &jednostavna{} },
+	"oprezna":     func(rnd *rand.Rand) Strategija { return &jednostavna{rezerva: 0.75} },
+}
+
+// ==== Nasumična strategija ====
+type nasumicna struct {
+	rnd *rand.Rand
+}
+
+func (s *nasumicna) Licitiraj(p Pogled) int {
+	if s.rnd.Intn(3) > 0 {
+		return preferans.Pas
+	}
+	return p.Dozvoljene[s.rnd.Intn(len(p.Dozvoljene))]
+}
+
+func (s *nasumicna) Stil(p Pogled) (rune, []string) {
+	adut := preferans.Suits[s.rnd.Intn(4)]
+	if len(p.Ruka) <= 10 {
+		return adut, nil
+	}
+	perm := s.rnd.Perm(len(p.Ruka))
+	return adut, []string{p.Ruka[perm[0]], p.Ruka[perm[1]]}
+}
+
+func (s *nasumicna) Prati(p Pogled) bool  { return s.rnd.Intn(2) == 0 }
+func (s *nasumicna) Kontra(p Pogled) bool { return s.rnd.Intn(6) == 0 }
+func (s *nasumicna) Baci(p Pogled) string { return p.Legalne[s.rnd.Intn(len(p.Legalne))] }
+
+// ==== Jednostavna strategija ====
+// Procenjuje broj štihova po visokim kartama i dužini aduta. Rezerva je broj
+// štihova preko potrebnih koje igrač traži pre nego što licitira.
+type jednostavna struct {
+	rezerva float64
+}
+
+// procena grubo broji štihove koje ruka nosi uz dati adut (0 znači bez aduta)
+func procena(ruka []string, adut rune) float64 {
+	var poBoji [5][]int // indeks je SuitOrder boje
+	for _, c := range ruka {
+		r, s := preferans.ParseCard(c)
+		poBoji[preferans.SuitOrder[s]] = append(poBoji[preferans.SuitOrder[s]], preferans.RankOrder[r])
+	}
+	ukupno := 0.0
+	for i, rangovi := range poBoji {
+		if len(rangovi) == 0 {
+			continue
+		}
+		boja := rune(0)
+		if i > 0 {
+			boja = preferans.Suits[i-1]
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(rangovi)))
+		n := len(rangovi)
+		for i, r := range rangovi {
+			// karta vredi ako je ima dovoljno "ispod" sebe da je zaštiti
+			visih := 8 - r
+			switch {
+			case visih == 0:
+				ukupno += 1
+			case visih == 1 && n >= 2:
+				ukupno += 0.75
+			case visih == 2 && n >= 3:
+				ukupno += 0.5
+			case visih == 3 && n >= 4 && i < 3:
+				ukupno += 0.25
+			}
+		}
+		if boja == adut && n > 3 {
+			ukupno += 0.9 * float64(n-3)
+		}
+		if adut == 0 && n >= 5 && rangovi[0] == 8 {
+			ukupno += 0.5 * float64(n-4)
+		}
+	}
+	return ukupno
+}
+
+// najboljiAdut vraća boju u kojoj ruka nosi najviše štihova
+func najboljiAdut(ruka []string) (rune, float64) {
+	naj, najProcena := preferans.Suits[0], -1.0
+	for _, boja := range preferans.Suits {
+		if p := procena(ruka, boja); p > najProcena {
+			naj, najProcena = boja, p
+		}
+	}
+	return naj, najProcena
+}
+
+// dobarBetl: nijedna karta iznad desetke i u svakoj boji koju ima postoji sedmica ili osmica
+func dobarBetl(ruka []string) bool {
+	najniza := map[rune]int{}
+	for _, c := range ruka {
+		r, s := preferans.ParseCard(c)
+		if preferans.RankOrder[r] > preferans.RankOrder["10"] {
+			return false
+		}
+		if v, ok := najniza[s]; !ok || preferans.RankOrder[r] < v {
+			najniza[s] = preferans.RankOrder[r]
+		}
+	}
+	for _, v := range najniza {
+		if v > 2 {
+			return false
+		}
+	}
+	return true
+}
+
+func ima(lista []int, v int) bool {
+	for _, x := range lista {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *jednostavna) Licitiraj(p Pogled) int {
+	_, uzAdut := najboljiAdut(p.Ruka)
+	bezAduta := procena(p.Ruka, 0)
+	switch {
+	case bezAduta >= 7+s.rezerva && ima(p.Dozvoljene, preferans.Sans):
+		return preferans.Sans
+	case dobarBetl(p.Ruka) && ima(p.Dozvoljene, preferans.Betl):
+		return preferans.Betl
+	case uzAdut >= 7+s.rezerva && ima(p.Dozvoljene, preferans.Igra):
+		return preferans.Igra
+	}
+	// talon u proseku donosi oko štih
+	moze := uzAdut + 1 - s.rezerva
+	if moze < 5.5 {
+		return preferans.Pas
+	}
+	najvise := preferans.MinBroj + int((moze-5.5)*2)
+	for _, v := range p.Dozvoljene {
+		if v >= preferans.MinBroj && v <= preferans.MaxBroj && v <= najvise {
+			return v
+		}
+	}
+	return preferans.Pas
+}
+
+func (s *jednostavna) Stil(p Pogled) (rune, []string) {
+	if p.Ponuda == preferans.Betl || p.Ponuda == preferans.Sans {
+		return 0, odbaciNajgore(p.Ruka, 0, p.Ponuda == preferans.Betl)
+	}
+	adut, _ := najboljiAdut(p.Ruka)
+	return adut, odbaciNajgore(p.Ruka, adut, false)
+}
+
+// odbaciNajgore bira dve karte čijim odbacivanjem procena ostaje najveća.
+// Razmatra samo pet najslabijih karata van aduta (u betlu pet najjačih).
+func odbaciNajgore(ruka []string, adut rune, betl bool) []string {
+	if len(ruka) <= 10 {
+		return nil
+	}
+	kandidati := append([]string{}, ruka...)
+	sort.SliceStable(kandidati, func(i, j int) bool {
+		ai, aj := preferans.Suit(kandidati[i]) == adut, preferans.Suit(kandidati[j]) == adut
+		if ai != aj {
+			return aj
+		}
+		ri, _ := preferans.ParseCard(kandidati[i])
+		rj, _ := preferans.ParseCard(kandidati[j])
+		if betl {
+			return preferans.RankOrder[ri] > preferans.RankOrder[rj]
+		}
+		return preferans.RankOrder[ri] < preferans.RankOrder[rj]
+	})
+	kandidati = kandidati[:5]
+
+	var naj []string
+	najProcena := 0.0
+	for i := 0; i < len(kandidati); i++ {
+		for j := i + 1; j < len(kandidati); j++ {
+			ostatak, _ := preferans.Ukloni(ruka, kandidati[i], kandidati[j])
+			v := procena(ostatak, adut)
+			if betl {
+				v = -v
+			}
+			if naj == nil || v > najProcena {
+				naj, najProcena = []string{kandidati[i], kandidati[j]}, v
+			}
+		}
+	}
+	return naj
+}
+
+func (s *jednostavna) Prati(p Pogled) bool {
+	if p.Ponuda == preferans.Betl {
+		return true
+	}
+	return procena(p.Ruka, p.Adut) >= 1.5
+}
+
+func (s *jednostavna) Kontra(p Pogled) bool {
+	v := procena(p.Ruka, p.Adut)
+	if p.Ja == p.Deklarant {
+		return v >= 8
+	}
+	if p.Nivo == 0 {
+		return v >= 3.5+s.rezerva
+	}
+	return v >= 4.5+s.rezerva
+}
+
+func (s *jednostavna) Baci(p Pogled) string {
+	legalne := p.Legalne
+	betlDeklarant := p.Ponuda == preferans.Betl && p.Ja == p.Deklarant
+	if len(p.Stih) == 0 {
+		if betlDeklarant {
+			return najniza(legalne)
+		}
+		if p.Ja == p.Deklarant && p.Adut != 0 {
+			for _, c := range legalne {
+				if preferans.Suit(c) == p.Adut {
+					return najvisa(legalne, p.Adut)
+				}
+			}
+		}
+		return najvisa(legalne, 0)
+	}
+	// najjeftinija karta koja nosi štih, ili najslabija ako se ne može uzeti
+	pobednicke := []string{}
+	gubitnicke := []string{}
+	for _, c := range legalne {
+		stih := append(append([]string{}, p.Stih...), c)
+		if preferans.PobednikStiha(stih, p.Adut) == len(stih)-1 {
+			pobednicke = append(pobednicke, c)
+		} else {
+			gubitnicke = append(gubitnicke, c)
+		}
+	}
+	if betlDeklarant {
+		if len(gubitnicke) > 0 {
+			return najvisaOd(gubitnicke)
+		}
+		return najniza(legalne)
+	}
+	if len(pobednicke) > 0 {
+		return najniza(pobednicke)
+	}
+	return najniza(legalne)
+}
+
+func najniza(karte []string) string {
+	naj := karte[0]
+	for _, c := range karte[1:] {
+		r1, _ := preferans.ParseCard(naj)
+		r2, _ := preferans.ParseCard(c)
+		if preferans.RankOrder[r2] < preferans.RankOrder[r1] {
+			naj = c
+		}
+	}
+	return naj
+}
+
+func najvisaOd(karte []string) string {
+	naj := karte[0]
+	for _, c := range karte[1:] {
+		r1, _ := preferans.ParseCard(naj)
+		r2, _ := preferans.ParseCard(c)
+		if preferans.RankOrder[r2] > preferans.RankOrder[r1] {
+			naj = c
+		}
+	}
+	return naj
+}
+
+// najvisa vraća najjaču kartu date boje, ili (za boju 0) najjaču kartu ruke
+func najvisa(karte []string, boja rune) string {
+	izbor := []string{}
+	for _, c := range karte {
+		if boja == 0 || preferans.Suit(c) == boja {
+			izbor = append(izbor, c)
+		}
+	}
+	if len(izbor) == 0 {
+		izbor = karte
+	}
+	return najvisaOd(izbor)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+)
+
+// ==== Dnevnik ====
+// Svaka linija nosi sobu, mesto, korisnika, podelu i tip poruke kad su poznati.
+// log-format=json prebacuje izlaz u JSON, log-nivo bira nivo (debug, info, warn,
+// error), a debug-soba=room3 beleži svaku primljenu i poslatu poruku protokola,
+// ali samo za tu sobu.
+var debugSoba string
+
+// initLog se zove posle provere konfiguracije, pa su format i nivo ispravni
+func initLog() {
+	var nivo slog.Level
+	nivo.UnmarshalText([]byte(konfig.LogNivo))
+	opcije := &slog.HandlerOptions{Level: nivo}
+	var h slog.Handler = slog.NewTextHandler(os.Stderr, opcije)
+	if konfig.LogFormat == "json" {
+		h = slog.NewJSONHandler(os.Stderr, opcije)
+	}
+	slog.SetDefault(slog.New(h))
+
+	debugSoba = konfig.DebugSoba
+	if debugSoba != "" {
+		slog.Warn("protokol se beleži za jednu sobu", "soba", debugSoba)
+	}
+}
+
+// log vraća dnevnik sa podacima o igraču i podeli za njegovim stolom
+func (p *Player) log() *slog.Logger {
+	l := slog.With("soba", p.room, "mesto", p.id, "podela", p.podela.Load())
+	if p.user != nil {
+		l = l.With("korisnik", p.user.Ime)
+	}
+	return l
+}
+
+// log vraća dnevnik sa podacima o sobi, za događaje koji nisu vezani za jedno
+// mesto; za igrača se koristi p.log. Zove se sa zaključanim r.mu.
+func (r *Room) log() *slog.Logger {
+	return slog.With("soba", r.id, "podela", r.dealCount)
+}
+
+// zapamtiPodelu prepisuje dealCount svima za stolom, da bi ga p.log čitao bez
+// brave sobe; zove se sa zaključanim r.mu
+func (r *Room) zapamtiPodelu() {
+	for _, p := range r.players {
+		p.podela.Store(int32(r.dealCount))
+	}
+	for _, g := range r.gledaoci {
+		g.podela.Store(int32(r.dealCount))
+	}
+}
+
+// podela je redni broj podele koja je u toku
+func (r *Room) podela() int {
+	if r.match == nil {
+		return 0
+	}
+	return r.match.podele + 1
+}
+
+// uSobu smešta igrača u sobu; mesto mora već biti dodeljeno. Veza pamti igrača
+// ako je soba pod protokolskim praćenjem.
+func (p *Player) uSobu(id string) {
+	p.room = id
+	if p.conn == nil {
+		return
+	}
+	if debugSoba != "" && id == debugSoba {
+		p.conn.prati(p)
+	} else {
+		p.conn.prati(nil)
+	}
+}
+
+// protokol beleži jednu poruku praćene sobe
+func protokol(l *slog.Logger, smer, tip string, data []byte) {
+	l.Info("protokol", "smer", smer, "tip", tip, "poruka", json.RawMessage(data))
+}
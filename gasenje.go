@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"multiplayer-game/preferans"
+)
+
+// ==== Gašenje ====
+// Na SIGTERM server prestaje da otvara nove stolove, javlja igračima koliko ima
+// do gašenja, čeka da se odigraju ruke u toku (najviše konfig.RokGasenja), snima
+// nezavršene mečeve u sobe.json i zatvara veze kodom 1012 (Service Restart).
+// Posle restarta sobe se vraćaju, a igrači preuzimaju svoja mesta sa /ws?soba=.
+var (
+	gasiSe        atomic.Bool
+	sobePutanja   = "sobe.json"
+	errGasiSe     = tr("gasi_se")
+	korakOdbrojav = 10 * time.Second
+)
+
+// SnimakSobe je meč koji nije završen kad je server ugašen
+type SnimakSobe struct {
+	ID         string              `json:"id"`
+	Pravila    preferans.Rules     `json:"pravila"`
+	Korisnici  [3]int              `json:"korisnici"` // ID naloga po mestu
+	Refe       [3]int              `json:"refe"`
+	Bule       [3]int              `json:"bule"`
+	Supe       [3][3]int           `json:"supe"`
+	Podele     int                 `json:"podele"`
+	Istorija   []preferans.Obracun `json:"istorija"`
+	StartIndex int                 `json:"start_index"`
+	Chat       []ChatPoruka        `json:"chat,omitempty"`
+}
+
+// ugasi vodi gašenje do kraja; vraća se kad su sve veze zatvorene
+func ugasi(serveri ...*http.Server) {
+	gasiSe.Store(true)
+	rok := time.Now().Add(konfig.RokGasenja)
+	slog.Info("gašenje počelo", "rok", konfig.RokGasenja)
+
+	for {
+		preostalo := time.Until(rok).Round(time.Second)
+		if preostalo <= 0 {
+			break
+		}
+		uToku := javiGasenje(preostalo)
+		if uToku == 0 {
+			break
+		}
+		slog.Info("čekaju se ruke u toku", "sobe", uToku, "preostalo", preostalo)
+		if sacekajRuke(min(korakOdbrojav, time.Until(rok))) {
+			break
+		}
+	}
+
+	if err := snimiSobe(); err != nil {
+		slog.Error("snimanje soba", "greska", err)
+	}
+	sveVeze.Range(func(k, _ any) bool {
+		k.(*veza).zatvoriSa(websocket.CloseServiceRestart, "server se restartuje")
+		return true
+	})
+	gotovo := make(chan struct{})
+	go func() {
+		pisaci.Wait()
+		close(gotovo)
+	}()
+	select {
+	case <-gotovo:
+	case <-time.After(2 * time.Second):
+		slog.Warn("neke veze nisu zatvorene na vreme")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, srv := range serveri {
+		if err := srv.Shutdown(ctx); err != nil {
+			slog.Error("gašenje HTTP servera", "adresa", srv.Addr, "greska", err)
+		}
+	}
+	slog.Info("server ugašen")
+}
+
+// javiGasenje šalje server_shutdown svim stolovima i vraća broj ruku u toku
+func javiGasenje(preostalo time.Duration) int {
+	mu.Lock()
+	defer mu.Unlock()
+	uToku := 0
+	for id, room := range rooms {
+		room.mu.Lock()
+		if room.rukaUToku() {
+			uToku++
+		}
+		room.broadcast(map[string]any{
+			"type":    "server_shutdown",
+			"message": tr("gasenje", preostalo.String()),
+			"rok":     int(preostalo.Seconds()),
+			"soba":    id,
+		})
+		room.mu.Unlock()
+	}
+	return uToku
+}
+
+// sacekajRuke javlja da li su se sve ruke odigrale u zadatom vremenu
+func sacekajRuke(d time.Duration) bool {
+	for kraj := time.Now().Add(d); time.Now().Before(kraj); time.Sleep(200 * time.Millisecond) {
+		if rukeUToku() == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func rukeUToku() int {
+	mu.Lock()
+	defer mu.Unlock()
+	n := 0
+	for _, room := range rooms {
+		room.mu.Lock()
+		if room.rukaUToku() {
+			n++
+		}
+		room.mu.Unlock()
+	}
+	return n
+}
+
+// rukaUToku javlja da li se za stolom igra podela koju vredi sačekati; ako je
+// neko izgubio vezu, ruka se ne može završiti. Zove se sa zaključanim r.mu.
+func (r *Room) rukaUToku() bool {
+	return r.deliSeRuka() && sviPovezani(r)
+}
+
+// snimiSobe upisuje nezavršene mečeve; ruka koja nije odigrana do roka se ne računa.
+// Turnirski stolovi se ne snimaju, njih turnir sam pravi posle restarta, a ni
+// stolovi za vežbanje.
+func snimiSobe() error {
+	mu.Lock()
+	snimci := []SnimakSobe{}
+	for id, room := range rooms {
+		room.mu.Lock()
+		if room.turnir == nil && room.vezbanje == nil && room.match != nil && !room.match.gotov && len(room.players) == 3 {
+			s := SnimakSobe{
+				ID:         id,
+				Pravila:    room.rules,
+				Korisnici:  room.match.korisnici,
+				Bule:       room.match.bule,
+				Supe:       room.match.supe,
+				Podele:     room.match.podele,
+				Istorija:   room.match.istorija,
+				StartIndex: room.startIndex,
+				Chat:       room.chat,
+			}
+			for _, p := range room.players {
+				s.Refe[p.id] = p.refe
+			}
+			snimci = append(snimci, s)
+		}
+		room.mu.Unlock()
+	}
+	mu.Unlock()
+	if len(snimci) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(snimci, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := sobePutanja + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	slog.Info("nezavršeni mečevi snimljeni", "sobe", len(snimci), "fajl", sobePutanja)
+	return os.Rename(tmp, sobePutanja)
+}
+
+// obnoviSobe vraća stolove snimljene pri gašenju. Stolovi čekaju da se sva tri
+// igrača vrate (preuzmiMesto), pa se tek onda deli.
+func obnoviSobe() error {
+	data, err := os.ReadFile(sobePutanja)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var snimci []SnimakSobe
+	if err := json.Unmarshal(data, &snimci); err != nil {
+		return fmt.Errorf("%s: %w", sobePutanja, err)
+	}
+	mu.Lock()
+	for _, s := range snimci {
+		room := &Room{id: s.ID, rules: s.Pravila, maxRefe: s.Pravila.MaxRefe, startIndex: s.StartIndex, pauza: true, chat: s.Chat}
+		for mesto, id := range s.Korisnici {
+			u := korisnici.get(id)
+			if u == nil {
+				continue
+			}
+			room.players = append(room.players, &Player{id: mesto, user: u, name: u.Ime, refe: s.Refe[mesto]})
+		}
+		if len(room.players) < 3 {
+			slog.Warn("sto se ne vraća, nalog ne postoji", "soba", s.ID)
+			continue
+		}
+		room.match = &Match{
+			room:      room,
+			bule:      s.Bule,
+			supe:      s.Supe,
+			podele:    s.Podele,
+			revans:    map[int]bool{},
+			istorija:  s.Istorija,
+			korisnici: s.Korisnici,
+		}
+		rooms[s.ID] = room
+	}
+	mu.Unlock()
+	slog.Info("vraćeni nezavršeni mečevi", "sobe", len(snimci))
+	// snimak važi samo za jedan restart
+	return os.Rename(sobePutanja, sobePutanja+".vracen")
+}
@@ -2,4 +2,7 @@ module multiplayer-game
 
 go 1.24.4
 
-require github.com/gorilla/websocket v1.5.3 // indirect
+require (
+	github.com/gorilla/websocket v1.5.3
+	golang.org/x/crypto v0.31.0
+)
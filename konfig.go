@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"multiplayer-game/preferans"
+)
+
+// ==== Konfiguracija ====
+// Vrednost se uzima redom: podrazumevana, pa fajl (-konfig ili WSPREF_KONFIG),
+// pa promenljiva okruženja, pa fleg. Fajl je ravan TOML: ključ = vrednost, bez sekcija.
+type Konfig struct {
+	Adresa           string
+	Static           string   // prazno: ugrađeni fajlovi; direktorijum: čitaj sa diska (frontend)
+	Poreklo          []string // dozvoljena porekla za /ws; "*" dozvoljava svako, prazno samo isti host
+	Pravila          string   // podrazumevana pravila kad klijent ne izabere
+	Jezik            string   // jezik poruka kad klijent ne izabere (sr, sr-Cyrl, en)
+	RokPisanja       time.Duration
+	ChatProzor       time.Duration
+	RokGasenja       time.Duration // koliko se posle SIGTERM čeka da se ruke odigraju
+	MaxPoruka        int           // bajtova u jednoj websocket poruci
+	PorukaUSekundi   int           // po vezi; nalet sme da bude dvostruko veći
+	IPPorukaUSekundi int           // zbir svih veza sa jedne adrese
+	VezaPoIP         int
+	MaxPrekrsaja     int    // odbačenih ili neispravnih poruka pre prekida veze
+	PrijavaUMinutu   int    // pokušaja prijave i registracije sa jedne adrese
+	Skladiste        string // DSN skladišta, za sada samo file:<direktorijum>
+	AdminToken       string
+	SessionKey       string
+	TLSSertifikat    string
+	TLSKljuc         string
+	HTTPAdresa       string // uz TLS: slušač koji preusmerava na HTTPS
+	WSURL            string // websocket adresa koju /config.js daje klijentu; prazno: ista adresa kao stranica
+	LogFormat        string
+	LogNivo          string
+	DebugSoba        string
+}
+
+var konfig = Konfig{
+	Adresa:           ":8080",
+	Pravila:          preferans.DefaultRules,
+	Jezik:            podrazumevaniJezik,
+	RokPisanja:       10 * time.Second,
+	ChatProzor:       10 * time.Second,
+	RokGasenja:       time.Minute,
+	MaxPoruka:        4096,
+	PorukaUSekundi:   10,
+	IPPorukaUSekundi: 40,
+	VezaPoIP:         20,
+	MaxPrekrsaja:     10,
+	PrijavaUMinutu:   10,
+	Skladiste:        "file:.",
+	LogFormat:        "text",
+	LogNivo:          "info",
+}
+
+type opcija struct {
+	ime  string // ključ u fajlu i ime flega
+	env  string
+	opis string
+	vr   flag.Value
+}
+
+func (k *Konfig) opcije() []opcija {
+	return []opcija{
+		{"adresa", "WSPREF_ADRESA", "adresa na kojoj server sluša", (*tekst)(&k.Adresa)},
+		{"static", "WSPREF_STATIC", "za rad na frontendu: direktorijum iz koga se čitaju index.html, game.js i karte umesto ugrađenih", (*tekst)(&k.Static)},
+		{"poreklo", "WSPREF_POREKLO", "dozvoljena porekla za websocket, odvojena zarezom (* za svako)", (*lista)(&k.Poreklo)},
+		{"pravila", "WSPREF_PRAVILA", "podrazumevana pravila (" + strings.Join(preferans.NaziviPravila(), ", ") + ")", (*tekst)(&k.Pravila)},
+		{"jezik", "WSPREF_JEZIK", "podrazumevani jezik poruka (" + strings.Join(Jezici, ", ") + ")", (*tekst)(&k.Jezik)},
+		{"rok-pisanja", "WSPREF_ROK_PISANJA", "najduže čekanje na slanje jedne poruke", (*trajanje)(&k.RokPisanja)},
+		{"chat-prozor", "WSPREF_CHAT_PROZOR", "prozor u kome igrač sme da pošalje 5 poruka", (*trajanje)(&k.ChatProzor)},
+		{"rok-gasenja", "WSPREF_ROK_GASENJA", "koliko se posle SIGTERM čeka da se ruke u toku odigraju", (*trajanje)(&k.RokGasenja)},
+		{"max-poruka", "WSPREF_MAX_PORUKA", "najveća websocket poruka u bajtovima", (*broj)(&k.MaxPoruka)},
+		{"poruka-u-sekundi", "WSPREF_PORUKA_U_SEKUNDI", "dozvoljene poruke u sekundi po vezi", (*broj)(&k.PorukaUSekundi)},
+		{"ip-poruka-u-sekundi", "WSPREF_IP_PORUKA_U_SEKUNDI", "dozvoljene poruke u sekundi sa jedne IP adrese", (*broj)(&k.IPPorukaUSekundi)},
+		{"veza-po-ip", "WSPREF_VEZA_PO_IP", "najviše istovremenih veza sa jedne IP adrese", (*broj)(&k.VezaPoIP)},
+		{"max-prekrsaja", "WSPREF_MAX_PREKRSAJA", "odbačenih ili neispravnih poruka pre prekida veze", (*broj)(&k.MaxPrekrsaja)},
+		{"prijava-u-minutu", "WSPREF_PRIJAVA_U_MINUTU", "pokušaja prijave i registracije u minutu sa jedne IP adrese", (*broj)(&k.PrijavaUMinutu)},
+		{"skladiste", "WSPREF_SKLADISTE", "skladište naloga, rejtinga, zapisa i turnira (file:<direktorijum>)", (*tekst)(&k.Skladiste)},
+		{"admin-token", "WSPREF_ADMIN_TOKEN", "token za /admin; bez njega je admin API isključen", (*tekst)(&k.AdminToken)},
+		{"session-key", "WSPREF_SESSION_KEY", "ključ za potpis kolačića; bez njega sesije ne preživljavaju restart", (*tekst)(&k.SessionKey)},
+		{"tls-sertifikat", "WSPREF_TLS_CERT", "putanja do TLS sertifikata", (*tekst)(&k.TLSSertifikat)},
+		{"tls-kljuc", "WSPREF_TLS_KEY", "putanja do TLS ključa", (*tekst)(&k.TLSKljuc)},
+		{"http-adresa", "WSPREF_HTTP_ADRESA", "uz TLS: adresa HTTP slušača koji preusmerava na HTTPS (npr. :80)", (*tekst)(&k.HTTPAdresa)},
+		{"ws-url", "WSPREF_WS_URL", "websocket adresa za klijente (npr. wss://preferans.example.com/ws); prazno: ista kao stranica", (*tekst)(&k.WSURL)},
+		{"log-format", "WSPREF_LOG_FORMAT", "format dnevnika: text ili json", (*tekst)(&k.LogFormat)},
+		{"log-nivo", "WSPREF_LOG_LEVEL", "nivo dnevnika: debug, info, warn, error", (*tekst)(&k.LogNivo)},
+		{"debug-soba", "WSPREF_DEBUG_SOBA", "soba za koju se beleži svaka poruka protokola", (*tekst)(&k.DebugSoba)},
+	}
+}
+
+// ucitajKonfig čita fajl, okruženje i flegove; greške iz svih izvora se vraćaju zajedno
+func ucitajKonfig(args []string) (Konfig, error) {
+	k := konfig
+	fs := flag.NewFlagSet("wspref", flag.ContinueOnError)
+	putanja := fs.String("konfig", os.Getenv("WSPREF_KONFIG"), "TOML fajl sa konfiguracijom")
+	// flegovi se prvo čitaju u zasebnu kopiju, da bi se primenili tek posle fajla i okruženja
+	izFlegova := konfig
+	for _, o := range izFlegova.opcije() {
+		fs.Var(o.vr, o.ime, fmt.Sprintf("%s (%s)", o.opis, o.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return k, err
+	}
+
+	opcije := k.opcije()
+	var greske []error
+	if *putanja != "" {
+		vrednosti, err := citajTOML(*putanja)
+		if err != nil {
+			return k, err
+		}
+		for _, o := range opcije {
+			if v, ok := vrednosti[o.ime]; ok {
+				if err := o.vr.Set(v); err != nil {
+					greske = append(greske, fmt.Errorf("%s: %s: %w", *putanja, o.ime, err))
+				}
+				delete(vrednosti, o.ime)
+			}
+		}
+		for ime := range vrednosti {
+			greske = append(greske, fmt.Errorf("%s: nepoznat ključ %q", *putanja, ime))
+		}
+	}
+	for _, o := range opcije {
+		if v, ok := os.LookupEnv(o.env); ok {
+			if err := o.vr.Set(v); err != nil {
+				greske = append(greske, fmt.Errorf("%s: %w", o.env, err))
+			}
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		for _, o := range opcije {
+			if o.ime == f.Name {
+				o.vr.Set(f.Value.String())
+			}
+		}
+	})
+	if len(greske) > 0 {
+		return k, errors.Join(greske...)
+	}
+	return k, k.proveri()
+}
+
+// proveri javlja sve neispravne vrednosti odjednom
+func (k *Konfig) proveri() error {
+	var greske []error
+	greska := func(ime, format string, a ...any) {
+		greske = append(greske, fmt.Errorf("%s: %s", ime, fmt.Sprintf(format, a...)))
+	}
+	if _, _, err := net.SplitHostPort(k.Adresa); err != nil {
+		greska("adresa", "%v", err)
+	}
+	if k.Static != "" {
+		if fi, err := os.Stat(k.Static); err != nil || !fi.IsDir() {
+			greska("static", "%q nije direktorijum", k.Static)
+		}
+	}
+	for _, p := range k.Poreklo {
+		if p == "*" {
+			continue
+		}
+		u, err := url.Parse(p)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.TrimSuffix(u.Path, "/") != "" {
+			greska("poreklo", "%q nije oblika https://domen[:port]", p)
+		}
+	}
+	if _, err := preferans.RulesZa(k.Pravila); err != nil {
+		greska("pravila", "%v", err)
+	}
+	if !slices.Contains(Jezici, k.Jezik) {
+		greska("jezik", "%q: mora biti jedan od %s", k.Jezik, strings.Join(Jezici, ", "))
+	}
+	if k.RokPisanja <= 0 {
+		greska("rok-pisanja", "mora biti veći od nule")
+	}
+	if k.ChatProzor <= 0 {
+		greska("chat-prozor", "mora biti veći od nule")
+	}
+	if k.RokGasenja < 0 {
+		greska("rok-gasenja", "ne sme biti negativan")
+	}
+	for _, o := range []struct {
+		ime string
+		v   int
+	}{
+		{"max-poruka", k.MaxPoruka},
+		{"poruka-u-sekundi", k.PorukaUSekundi},
+		{"ip-poruka-u-sekundi", k.IPPorukaUSekundi},
+		{"veza-po-ip", k.VezaPoIP},
+		{"max-prekrsaja", k.MaxPrekrsaja},
+		{"prijava-u-minutu", k.PrijavaUMinutu},
+	} {
+		if o.v <= 0 {
+			greska(o.ime, "mora biti veći od nule")
+		}
+	}
+	if dir, ok := strings.CutPrefix(k.Skladiste, "file:"); !ok {
+		greska("skladiste", "%q: podržano je samo file:<direktorijum>", k.Skladiste)
+	} else if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		greska("skladiste", "%q nije direktorijum", dir)
+	}
+	if (k.TLSSertifikat == "") != (k.TLSKljuc == "") {
+		greska("tls", "sertifikat i ključ se zadaju zajedno")
+	}
+	for _, putanja := range []string{k.TLSSertifikat, k.TLSKljuc} {
+		if putanja == "" {
+			continue
+		}
+		if f, err := os.Open(putanja); err != nil {
+			greska("tls", "%v", err)
+		} else {
+			f.Close()
+		}
+	}
+	if k.HTTPAdresa != "" {
+		if k.TLSSertifikat == "" {
+			greska("http-adresa", "preusmeravanje na HTTPS traži tls-sertifikat i tls-kljuc")
+		}
+		if _, _, err := net.SplitHostPort(k.HTTPAdresa); err != nil {
+			greska("http-adresa", "%v", err)
+		}
+	}
+	if k.WSURL != "" {
+		u, err := url.Parse(k.WSURL)
+		if err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+			greska("ws-url", "%q nije oblika wss://domen/ws", k.WSURL)
+		}
+	}
+	if k.LogFormat != "text" && k.LogFormat != "json" {
+		greska("log-format", "%q: mora biti text ili json", k.LogFormat)
+	}
+	var nivo slog.Level
+	if err := nivo.UnmarshalText([]byte(k.LogNivo)); err != nil {
+		greska("log-nivo", "%q: mora biti debug, info, warn ili error", k.LogNivo)
+	}
+	return errors.Join(greske...)
+}
+
+// direktorijumSkladista vraća direktorijum iz file: DSN-a
+func (k *Konfig) direktorijumSkladista() string {
+	dir, _ := strings.CutPrefix(k.Skladiste, "file:")
+	return dir
+}
+
+// proveriPoreklo je CheckOrigin za websocket
+func proveriPoreklo(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// klijenti van pregledača (CLI, test opterećenja) ne šalju Origin
+		return true
+	}
+	if len(konfig.Poreklo) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+	for _, p := range konfig.Poreklo {
+		if p == "*" || strings.EqualFold(strings.TrimSuffix(p, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// postaviSkladiste smešta sve fajlove sa podacima u zadati direktorijum
+func postaviSkladiste(dir string) {
+	korisniciPutanja = filepath.Join(dir, "korisnici.json")
+	rejtingPutanja = filepath.Join(dir, "rejting.json")
+	rukePutanja = filepath.Join(dir, "ruke.jsonl")
+	meceviPutanja = filepath.Join(dir, "mecevi.jsonl")
+	turniriPutanja = filepath.Join(dir, "turniri.json")
+	sobePutanja = filepath.Join(dir, "sobe.json")
+}
+
+// ==== Vrednosti opcija ====
+type tekst string
+
+func (t *tekst) String() string     { return string(*t) }
+func (t *tekst) Set(s string) error { *t = tekst(s); return nil }
+
+type trajanje time.Duration
+
+func (t *trajanje) String() string { return time.Duration(*t).String() }
+func (t *trajanje) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("%q nije trajanje (npr. 10s, 2m)", s)
+	}
+	*t = trajanje(d)
+	return nil
+}
+
+type broj int
+
+func (b *broj) String() string { return strconv.Itoa(int(*b)) }
+func (b *broj) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%q nije ceo broj", s)
+	}
+	*b = broj(n)
+	return nil
+}
+
+type lista []string
+
+func (l *lista) String() string { return strings.Join(*l, ",") }
+func (l *lista) Set(s string) error {
+	*l = nil
+	for _, deo := range strings.Split(s, ",") {
+		if deo = strings.TrimSpace(deo); deo != "" {
+			*l = append(*l, deo)
+		}
+	}
+	return nil
+}
+
+// ==== TOML ====
+// citajTOML čita ravan TOML: niske, brojeve, bool i nizove niski. Vrednosti se
+// vraćaju kao tekst koji opcije same tumače; niz postaje lista odvojena zarezom.
+func citajTOML(putanja string) (map[string]string, error) {
+	f, err := os.Open(putanja)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parsirajTOML(f, putanja)
+}
+
+func parsirajTOML(r io.Reader, ime string) (map[string]string, error) {
+	out := map[string]string{}
+	sc := bufio.NewScanner(r)
+	for br := 1; sc.Scan(); br++ {
+		red := strings.TrimSpace(sc.Text())
+		if red == "" || strings.HasPrefix(red, "#") {
+			continue
+		}
+		if strings.HasPrefix(red, "[") {
+			return nil, fmt.Errorf("%s:%d: sekcije nisu podržane", ime, br)
+		}
+		kljuc, vrednost, ok := strings.Cut(red, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: očekuje se ključ = vrednost", ime, br)
+		}
+		kljuc = strings.TrimSpace(kljuc)
+		v, err := tomlVrednost(strings.TrimSpace(vrednost))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s: %w", ime, br, kljuc, err)
+		}
+		if _, postoji := out[kljuc]; postoji {
+			return nil, fmt.Errorf("%s:%d: ključ %q je već zadat", ime, br, kljuc)
+		}
+		out[kljuc] = v
+	}
+	return out, sc.Err()
+}
+
+func tomlVrednost(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		v, ostatak, err := tomlNiska(s)
+		if err != nil {
+			return "", err
+		}
+		if ostatak = strings.TrimSpace(ostatak); ostatak != "" && !strings.HasPrefix(ostatak, "#") {
+			return "", fmt.Errorf("višak posle niske: %q", ostatak)
+		}
+		return v, nil
+	case strings.HasPrefix(s, "["):
+		var delovi []string
+		s = strings.TrimSpace(s[1:])
+		for !strings.HasPrefix(s, "]") {
+			v, ostatak, err := tomlNiska(s)
+			if err != nil {
+				return "", fmt.Errorf("niz sme da sadrži samo niske")
+			}
+			delovi = append(delovi, v)
+			s = strings.TrimSpace(ostatak)
+			s = strings.TrimSpace(strings.TrimPrefix(s, ","))
+			if s == "" {
+				return "", fmt.Errorf("niz nije zatvoren (nizovi moraju biti u jednom redu)")
+			}
+		}
+		return strings.Join(delovi, ","), nil
+	default:
+		s, _, _ = strings.Cut(s, "#")
+		s = strings.TrimSpace(s)
+		if _, err := strconv.ParseFloat(s, 64); err != nil && s != "true" && s != "false" {
+			return "", fmt.Errorf("nepoznata vrednost %q (niske idu pod navodnike)", s)
+		}
+		return s, nil
+	}
+}
+
+// tomlNiska čita nisku pod navodnicima sa početka s i vraća ostatak
+func tomlNiska(s string) (string, string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", "", fmt.Errorf("očekuje se niska")
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			v, err := strconv.Unquote(s[:i+1])
+			return v, s[i+1:], err
+		}
+	}
+	return "", "", fmt.Errorf("niska nije zatvorena")
+}
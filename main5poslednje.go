@@ -2,160 +2,260 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"math/rand"
+	"log/slog"
+	"maps"
+	"math"
 	"net/http"
-	"sort"
+	"os"
+	"os/signal"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/websocket"
+
+	"multiplayer-game/preferans"
 )
 
 // ==== Strukture ====
 type Player struct {
-	conn         *websocket.Conn
-	room         string
-	cards        []string
-	bidValue     int
-	bidDeclared  bool
-	passed       bool
-	id           int
-	name         string
-	prihvatio    bool   // da li je prihvatio igru
-	refe         int    // broj refea
-	declaredGame string // "igra", "betl", "sans"
-	kontrirao    bool   // da li je dao kontru
+	conn        *veza
+	room        string
+	cards       []string
+	id          int
+	name        string
+	prihvatio   bool  // da li je prihvatio igru
+	refe        int   // broj refea
+	kontrirao   bool  // da li je dao kontru
+	user        *User // ulogovani korisnik koji sedi na ovom mestu
+	gledalac    bool  // gleda sto, ne igra
+	povezan     bool  // veza je otvorena
+	zamena      int   // ko sme da preuzme mesto posle prekida veze (vidi preuzmiMesto)
+	izbacen     int   // nalog koji je administrator udaljio sa ovog mesta
+	bot         bool  // mesto igra server (vidi vezbanje.go)
+	chatVremena []time.Time
+	podela      atomic.Int32 // dealCount sobe, za dnevnik (vidi dnevnik.go)
 }
 
 type Room struct {
-	talonOtkriven      bool
-	players            []*Player
-	talon              []string
-	bids               int
-	startIndex         int
-	currentBidIndex    int
-	highestBid         int
-	highestBidder      *Player
-	auctionDone        bool
-	passCount          int
-	dealCount          int
-	kontraStatus       int // 0: nema, 1: kontra, 2: rekontra, 3: subkontra
-	kontraBy           int // ID poslednjeg koji je rekao kontru
-	kontraActive       bool
-	prihvatili         int // broj igrača koji prate
-	cekamoPracenje     bool
-	maxRefe            int
-	adut               string
-	potvrdaOdigravanja bool  // čeka se potvrda deklaranta
-	cekamoKontru       int   // broj odgovora na prompt za kontru
-	kontraPlayers      []int // ID-evi igrača koji su dali kontru/rekontru/subkontru
-	mu                 sync.Mutex
+	id              string
+	faza            Faza // vidi stanje.go
+	players         []*Player
+	talon           []string
+	bids            int
+	startIndex      int
+	licitacija      *preferans.Licitacija // tok licitacije ove podele
+	highestBid      int
+	highestBidder   *Player
+	ponude          []Ponuda // licitacija ove podele redom
+	dealCount       int
+	kontraStatus    int // 0: nema, 1: kontra, 2: rekontra, 3: subkontra
+	kontraBy        int // ID poslednjeg koji je rekao kontru
+	kontraActive    bool
+	prihvatili      int // broj igrača koji prate
+	maxRefe         int
+	adut            string
+	kontraPlayers   []int // ID-evi igrača koji su dali kontru/rekontru/subkontru
+	rules           preferans.Rules
+	match           *Match
+	igra            *preferans.Odigravanje // štihovi ruke koja se igra, nil van igre
+	turnir          *turnirskiSto          // nil za obične sobe
+	gledaoci        []*Player
+	chat            []ChatPoruka    // poslednje poruke igrača
+	chatGledalaca   []ChatPoruka    // kanal koji igrači ne vide
+	pauza           bool            // nova podela čeka: server se gasi ili se igrači vraćaju posle restarta
+	talonUzet       bool            // deklarant je uzeo talon (vidi vidljivost.go)
+	skart           []string        // karte koje je deklarant odbacio, vidi ih samo on
+	odigrane        map[string]bool // karte bačene na sto u ovoj podeli
+	otkrivena       *Player         // deklarant koji je uz zahtev otvorio karte
+	zahtev          *zahtev         // zahtev deklaranta koji čeka odgovor protivnika
+	zapisZahteva    *ZapisZahteva   // prihvaćen zahtev, upisuje se uz ruku
+	trening         bool            // soba za učenje, igrači smeju da traže savet
+	saveti          [3]int          // broj saveta po mestu u ovoj podeli
+	spil            []string        // zadat špil za sledeću podelu umesto mešanja
+	podeljeno       []string        // špil ove podele: po 10 karata za mesta 0–2, pa talon
+	prvi            int             // ko je u ovoj podeli prvi licitirao
+	bacene          []string        // karte ove podele redom kojim su bačene
+	pitanZaPracenje int             // protivnik koji sada odlučuje da li prati
+	kontraNaRedu    int             // ko sada odgovara na kontru (vidi odgovorNaKontru)
+	vezbanje        *vezbanje       // nil osim u sobi za vežbanje sa botovima
+	poslovi         []posaoVanBrave // pretrage koje čekaju da se soba otključa
+	mu              sync.Mutex
 }
 
 var (
 	upgrader = websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
+		CheckOrigin: proveriPoreklo,
 	}
 	rooms = make(map[string]*Room)
 	mu    sync.Mutex
 )
 
-var deck = []string{
-	"7♠", "8♠", "9♠", "10♠", "J♠", "Q♠", "K♠", "A♠",
-	"7♦", "8♦", "9♦", "10♦", "J♦", "Q♦", "K♦", "A♦",
-	"7♥", "8♥", "9♥", "10♥", "J♥", "Q♥", "K♥", "A♥",
-	"7♣", "8♣", "9♣", "10♣", "J♣", "Q♣", "K♣", "A♣",
+// broadcast šalje poruku svima za stolom; svako dobija samo karte koje sme da vidi
+func (r *Room) broadcast(msg map[string]any) {
+	for _, p := range r.players {
+		r.posalji(p, msg)
+	}
+	for _, g := range r.gledaoci {
+		r.posalji(g, msg)
+	}
 }
 
-var rankOrder = map[string]int{
-	"7": 1, "8": 2, "9": 3, "10": 4, "J": 5, "Q": 6, "K": 7, "A": 8,
+// igrac vraća igrača sa datim ID-jem mesta
+func (r *Room) igrac(id int) *Player {
+	for _, p := range r.players {
+		if p.id == id {
+			return p
+		}
+	}
+	return &Player{id: id}
 }
 
-var suitOrder = map[rune]int{
-	'♠': 1, '♦': 2, '♥': 3, '♣': 4,
+// sedi proverava da li korisnik već ima mesto za ovim stolom
+func (r *Room) sedi(u *User) bool {
+	for _, p := range r.players {
+		if p.user != nil && p.user.ID == u.ID {
+			return true
+		}
+	}
+	return false
 }
 
-func (r *Room) broadcast(msg map[string]any) {
+// imena vraća imena igrača po mestima
+func (r *Room) imena() []string {
+	out := make([]string, 3)
 	for _, p := range r.players {
-		p.conn.WriteJSON(msg)
+		out[p.id] = p.ime()
 	}
+	return out
 }
 
-func sortCards(cards []string) {
-	sort.Slice(cards, func(i, j int) bool {
-		r1, s1 := parseCard(cards[i])
-		r2, s2 := parseCard(cards[j])
-		if suitOrder[s1] != suitOrder[s2] {
-			return suitOrder[s1] < suitOrder[s2]
+// rejtingStola vraća rejting igrača po mestima
+func (r *Room) rejtingStola() []float64 {
+	out := make([]float64, 3)
+	for _, p := range r.players {
+		if p.user != nil {
+			out[p.id] = rejtinzi.vrednost(p.user.ID)
 		}
-		return rankOrder[r1] < rankOrder[r2]
-	})
+	}
+	return out
 }
 
-func parseCard(card string) (rank string, suit rune) {
-	runes := []rune(card)
-	if len(runes) == 3 {
-		return string(runes[0:2]), runes[2]
+func (p *Player) ime() string {
+	if p.name != "" {
+		return p.name
 	}
-	return string(runes[0]), runes[1]
+	return fmt.Sprintf("Igrač %d", p.id)
 }
 
 func main() {
+	k, err := ucitajKonfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("neispravna konfiguracija:\n%v", err)
+	}
+	konfig = k
+	initLog()
+	for _, naziv := range preferans.NaziviPravila() {
+		if err := preferans.Presets[naziv].Validate(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	initSessions()
+	initAdmin()
+	postaviSkladiste(konfig.direktorijumSkladista())
+	if korisnici, err = loadUsers(korisniciPutanja); err != nil {
+		log.Fatal(err)
+	}
+	if rejtinzi, err = loadRatings(rejtingPutanja); err != nil {
+		log.Fatal(err)
+	}
+	if zapisi, err = loadStats(); err != nil {
+		log.Fatal(err)
+	}
+	if turniri, err = loadTournaments(turniriPutanja); err != nil {
+		log.Fatal(err)
+	}
+	for _, t := range turniri.turniri {
+		if t.Stanje == turnirUToku {
+			t.napraviSobe()
+		}
+	}
+	if err := obnoviSobe(); err != nil {
+		log.Fatal(err)
+	}
+	http.HandleFunc("/api/register", handleRegister)
+	http.HandleFunc("/api/login", handleLogin)
+	http.HandleFunc("/api/logout", handleLogout)
+	http.HandleFunc("/api/me", handleMe)
+	http.HandleFunc("GET /api/players/{id}/rating", handleRating)
+	http.HandleFunc("GET /api/players/{id}/stats", handleStats)
+	http.HandleFunc("GET /api/leaderboard", handleLeaderboard)
+	http.HandleFunc("GET /api/tournaments", handleTournamentList)
+	http.HandleFunc("POST /api/tournaments", handleTournamentCreate)
+	http.HandleFunc("GET /api/tournaments/{id}", handleTournament)
+	http.HandleFunc("POST /api/tournaments/{id}/register", handleTournamentRegister)
+	http.HandleFunc("POST /api/tournaments/{id}/start", handleTournamentStart)
+	http.HandleFunc("GET /api/vezbanje", handleVezbanjeRuke)
+	http.HandleFunc("POST /api/vezbanje", handleVezbanje)
+	registerAdmin()
+	http.HandleFunc("GET /metrics", handleMetrics)
 	http.HandleFunc("/ws", handleWebSocket)
-	http.Handle("/cards/", http.StripPrefix("/cards/", http.FileServer(http.Dir("static/cards"))))
-	http.Handle("/", http.FileServer(http.Dir("./static"))) // servira index.html i game.js
-	fmt.Println("Server running at http://localhost:8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
-}
-func startAuction(r *Room) {
-	// 1. Podela špila
-	deckCopy := append([]string(nil), deck...)
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(deckCopy), func(i, j int) {
-		deckCopy[i], deckCopy[j] = deckCopy[j], deckCopy[i]
-	})
-
-	r.talon = deckCopy[:2]
-	for i, p := range r.players {
-		p.cards = deckCopy[2+i*10 : 2+(i+1)*10]
-		sortCards(p.cards)
-		p.conn.WriteJSON(map[string]any{
-			"type":  "your_cards",
-			"cards": p.cards,
-		})
-		p.bidValue = 0
-		p.bidDeclared = false
-		p.passed = false
-		p.declaredGame = ""
-		p.kontrirao = false
-		p.prihvatio = false
-		//log.Printf("Igraču %d podeljeno: %v\n", p.id, p.cards)
+	static, err := staticHandler()
+	if err != nil {
+		log.Fatal(err)
 	}
+	http.Handle("/", static) // index.html, game.js, style.css i karte
+	http.HandleFunc("GET /config.js", handleConfigJS)
 
-	r.passCount = 0
-	r.bids = 0
-	r.highestBid = 0
-	r.highestBidder = nil
-	r.auctionDone = false
-	r.currentBidIndex = r.startIndex
+	srv := &http.Server{Addr: konfig.Adresa}
+	serveri := []*http.Server{srv}
+	if konfig.TLSSertifikat != "" {
+		sert, err := ucitajSertifikate(konfig.TLSSertifikat, konfig.TLSKljuc)
+		if err != nil {
+			log.Fatal(err)
+		}
+		srv.TLSConfig = &tls.Config{GetCertificate: sert.GetCertificate}
+		go pokreni(srv, func() error { return srv.ListenAndServeTLS("", "") })
+		slog.Info("server radi", "adresa", konfig.Adresa, "tls", true)
+		if konfig.HTTPAdresa != "" {
+			preusmeri := &http.Server{Addr: konfig.HTTPAdresa, Handler: http.HandlerFunc(preusmeriNaHTTPS)}
+			serveri = append(serveri, preusmeri)
+			go pokreni(preusmeri, preusmeri.ListenAndServe)
+			slog.Info("HTTP preusmerava na HTTPS", "adresa", konfig.HTTPAdresa)
+		}
+	} else {
+		go pokreni(srv, srv.ListenAndServe)
+		slog.Info("server radi", "adresa", konfig.Adresa)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	<-ctx.Done()
+	stop() // drugi signal prekida odmah
+	ugasi(serveri...)
+}
 
-	r.broadcast(map[string]any{
-		"type":    "auction_start",
-		"message": "Počinje licitacija. Igrač koji je na redu neka ponudi ili kaže pas.",
-		"player":  r.players[r.currentBidIndex].id,
-	})
+func pokreni(srv *http.Server, slusaj func() error) {
+	if err := slusaj(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("%s: %v", srv.Addr, err)
+	}
 }
-func assignToRoom(p *Player) string {
+func assignToRoom(p *Player, rules preferans.Rules, trening bool) string {
 	mu.Lock()
 	defer mu.Unlock()
 
 	for id, room := range rooms {
-		if len(room.players) < 3 {
+		if room.turnir == nil && len(room.players) < 3 && room.rules.Naziv == rules.Naziv && room.trening == trening && !room.sedi(p.user) {
 			// Pronađi zauzete ID-jeve u sobi
 			usedIDs := map[int]bool{}
 			for _, pl := range room.players {
@@ -171,134 +271,402 @@ func assignToRoom(p *Player) string {
 				}
 			}
 
+			room.mu.Lock()
+			defer room.mu.Unlock()
 			p.id = newID
 			room.players = append(room.players, p)
-			p.room = id
+			p.uSobu(id)
 
-			// Pošalji igraču njegov ID
-			p.conn.WriteJSON(map[string]any{
-				"type": "you_are",
-				"id":   p.id,
+			// Pošalji igraču njegov ID, a svima ko sedi za stolom
+			room.posalji(p, map[string]any{
+				"type":    "you_are",
+				"id":      p.id,
+				"ime":     p.ime(),
+				"pravila": room.rules,
+				"trening": room.trening,
+			})
+			room.broadcast(map[string]any{
+				"type":    "igraci",
+				"message": tr("seo_za_sto", p.ime()),
+				"imena":   room.imena(),
+				"rejting": room.rejtingStola(),
 			})
 
-			// Ako je soba sada puna, može da počne igra
+			// Ako je soba sada puna, može da počne meč
 			if len(room.players) == 3 {
+				room.match = newMatch(room)
 				dealCards(room)
 			}
+			room.javiStanje()
 
 			return id
 		}
 	}
 
-	// Kreiraj novu sobu ako nema mesta u postojećim
+	// Kreiraj novu sobu ako nema mesta u postojećim; sobe vraćene posle restarta
+	// ili zatvorene preko admina ostavljaju rupe u brojevima
 	newRoomID := fmt.Sprintf("room%d", len(rooms)+1)
-	rooms[newRoomID] = &Room{players: []*Player{p}}
-	p.room = newRoomID
+	for n := len(rooms) + 2; rooms[newRoomID] != nil; n++ {
+		newRoomID = fmt.Sprintf("room%d", n)
+	}
+	room := &Room{id: newRoomID, players: []*Player{p}, rules: rules, maxRefe: rules.MaxRefe, trening: trening}
+	rooms[newRoomID] = room
 	p.id = 0
+	p.uSobu(newRoomID)
 
 	// Pošalji igraču njegov ID
-	p.conn.WriteJSON(map[string]any{
-		"type": "you_are",
-		"id":   p.id,
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	room.posalji(p, map[string]any{
+		"type":    "you_are",
+		"id":      p.id,
+		"ime":     p.ime(),
+		"pravila": rules,
+		"trening": trening,
 	})
 
 	return newRoomID
 }
-func shuffleCards(cards []string) []string {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	shuffled := make([]string, len(cards))
-	perm := r.Perm(len(cards))
-	for i, v := range perm {
-		shuffled[v] = cards[i]
-	}
-	return shuffled
-}
 
+// dealCards mora da se zove sa zaključanim r.mu
 func dealCards(r *Room) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	shuffled := shuffleCards(deck)
-
-	for i, p := range r.players {
-		p.cards = append([]string{}, shuffled[i*10:(i+1)*10]...)
-		p.bidDeclared = false
-		p.passed = false
-		p.declaredGame = ""
-		p.conn.WriteJSON(map[string]any{
+	r.faza = fazaCekanje
+	if gasiSe.Load() {
+		r.pauza = true
+		r.broadcast(map[string]any{
+			"type":    "info",
+			"message": tr("gasi_se_pauza"),
+		})
+		return
+	}
+	shuffled := preferans.ShuffleCards(preferans.Deck, nil)
+	if r.vezbanje != nil && !r.vezbanje.podeli(r) {
+		return
+	}
+	if r.spil != nil {
+		shuffled, r.spil = r.spil, nil
+	}
+	if r.turnir != nil {
+		// turnirski stolovi dobijaju iste karte i istog prvog igrača na istoj podeli
+		karte, ok := r.turnir.zapocniPodelu(r)
+		if !ok {
+			r.turnir.zavrsiSto(r)
+			return
+		}
+		shuffled = karte
+		r.startIndex = (r.turnir.podela - 1) % 3
+	}
+	rasporediKarte(r, shuffled)
+	for _, p := range r.players {
+		r.posalji(p, map[string]any{
 			"type":  "your_cards",
 			"cards": p.cards,
 		})
 	}
-	allowedActions := []string{"pass", "2", "3", "4", "5", "6", "7", "igra", "betl", "sans"}
-
-	r.players[r.currentBidIndex].conn.WriteJSON(map[string]any{
-		"type":    "your_turn",
-		"actions": allowedActions,
-		"message": "Tvoj je red za licitaciju, izaberi ponudu ili pas.",
-	})
-	r.talon = append([]string{}, shuffled[30:32]...)
-	r.passCount = 0
-	r.highestBid = 0
-	r.highestBidder = nil
-	r.currentBidIndex = r.startIndex
 
 	r.broadcast(map[string]any{
 		"type":    "info",
-		"message": "Karte su podeljene, počinje licitacija.",
+		"message": tr("karte_podeljene"),
 	})
 
-	r.players[r.currentBidIndex].conn.WriteJSON(map[string]any{
-		"type":  "your_turn",
-		"index": r.currentBidIndex,
+	r.pozovi(r.players[r.licitacija.NaPotezu], map[string]any{
+		"type":    "your_turn",
+		"index":   r.licitacija.NaPotezu,
+		"message": tr("tvoj_red_licitacija"),
 	})
 }
+
+// rasporediKarte deli karte i vraća sto na početak licitacije, bez poruka
+func rasporediKarte(r *Room, shuffled []string) {
+	r.dealCount++
+	r.zapamtiPodelu()
+	r.licitacija = preferans.NovaLicitacija(r.startIndex, r.rules)
+	r.podeljeno = shuffled
+	r.prvi = r.startIndex
+
+	for i, p := range r.players {
+		p.cards = append([]string{}, shuffled[i*10:(i+1)*10]...)
+		p.prihvatio, p.kontrirao = false, false
+	}
+	r.talon = append([]string{}, shuffled[30:32]...)
+	r.highestBid = 0
+	r.highestBidder = nil
+	r.kontraStatus = 0
+	r.kontraActive = false
+	r.kontraPlayers = nil
+	r.talonUzet = false
+	r.skart = nil
+	r.odigrane = map[string]bool{}
+	r.otkrivena = nil
+	r.zahtev = nil
+	r.zapisZahteva = nil
+	r.saveti = [3]int{}
+	r.bacene = nil
+	r.ponude = nil
+	r.faza = fazaLicitacija
+	r.adut = ""
+}
+
 func startGame(r *Room) {
 	if r == nil || r.highestBidder == nil {
-		log.Println("startGame pozvan bez validnog highestBidder-a")
+		r.log().Error("startGame pozvan bez validnog highestBidder-a")
 		return
 	}
-	if r.talonOtkriven {
+	pripremiIgru(r)
+	objaviIgru(r)
+}
+
+// pripremiIgru pravi odigravanje iz ugovora i ruku, bez poruka
+func pripremiIgru(r *Room) {
+	r.startIndex = (r.startIndex + 1) % 3
+
+	var ruke [3][]string
+	for _, p := range r.players {
+		ruke[p.id] = p.cards
+	}
+	adut := preferans.AdutIzStila(r.adut)
+	if !preferans.BiraAdut(r.highestBid) {
+		adut = 0
+	}
+	r.igra = preferans.NovoOdigravanje(ruke, adut, r.highestBidder.id)
+	r.faza = fazaIgra
+	if r.vezbanje != nil {
+		r.vezbanje.zapamtiPocetak(r)
+	}
+}
+
+// objaviIgru javlja početak igre, šalje ruke i poziva prvog na potez
+func objaviIgru(r *Room) {
+	if r.talonUzet && r.rules.TalonOtkriven {
 		r.broadcast(map[string]any{
 			"type":  "talon_info",
 			"talon": r.talon,
 		})
 	}
-	r.startIndex = (r.startIndex + 1) % 3
-
+	var naziv any = r.adut
+	if !preferans.BiraAdut(r.highestBid) {
+		naziv = ugovor(r.highestBid)
+	}
 	r.broadcast(map[string]any{
 		"type":    "start_game",
-		"message": fmt.Sprintf("Igrač %d počinje igru sa adutom %s", r.highestBidder.id, r.adut),
+		"message": tr("pocetak_igre", r.highestBidder.ime(), naziv),
 	})
 
 	for _, p := range r.players {
-		sortCards(p.cards)
-		p.conn.WriteJSON(map[string]any{
+		preferans.SortCards(p.cards)
+		r.posalji(p, map[string]any{
 			"type":  "your_cards",
 			"cards": p.cards,
 		})
 	}
 
+	javiPotez(r)
+}
+
+// ==== Praćenje i kontra ====
+// Posle potvrde (ili škarta) protivnici redom, počev od igrača posle
+// deklaranta, kažu da li prate. Ako ne prati niko, deklarant prolazi bez igre.
+// Kontru daje jedan pratilac, prvi po redu koji je hoće; na kontru deklarant
+// sme da da rekontru, a na rekontru onaj ko je dao kontru subkontru, dok
+// pravila (MaxKontra) dozvoljavaju.
+
+// pitajZaPracenje pita prvog protivnika; zove se sa zaključanim r.mu
+func (r *Room) pitajZaPracenje() {
+	r.faza = fazaPracenje
+	for _, p := range r.players {
+		p.prihvatio = false
+	}
+	r.pitanZaPracenje = (r.highestBidder.id + 1) % 3
+	if r.highestBidder.refe > 0 {
+		r.broadcast(map[string]any{
+			"type":    "info",
+			"message": tr("igra_sa_refeom", r.highestBidder.ime()),
+		})
+	}
+	r.posalji(r.highestBidder, map[string]any{
+		"type":    "info",
+		"message": tr("ceka_pracenje"),
+	})
+	r.pozoviZaPracenje()
+}
+
+func (r *Room) pozoviZaPracenje() {
+	r.pozovi(r.players[r.pitanZaPracenje], map[string]any{
+		"type":    "prati_prompt",
+		"message": tr("prati_pitanje", r.highestBidder.ime(), ugovor(r.highestBid)),
+	})
+}
+
+// odgovorNaPracenje beleži odluku protivnika na potezu i pita sledećeg, ili
+// prelazi na kontru kad su oba odgovorila; zove se sa zaključanim r.mu
+func (r *Room) odgovorNaPracenje(p *Player, prati bool) {
+	p.prihvatio = prati
+	kod := "ne_prati"
+	if prati {
+		kod = "prati"
+	}
 	r.broadcast(map[string]any{
-		"type":    "turn",
-		"message": fmt.Sprintf("Igrač %d je na potezu. Baci kartu.", r.highestBidder.id),
-		"player":  r.highestBidder.id,
+		"type":    "info",
+		"message": tr(kod, p.ime()),
 	})
+	if sledeci := (p.id + 1) % 3; sledeci != r.highestBidder.id {
+		r.pitanZaPracenje = sledeci
+		r.pozoviZaPracenje()
+		return
+	}
+	pratilaca := 0
+	for _, pl := range r.players {
+		if pl.prihvatio {
+			pratilaca++
+		}
+	}
+	if pratilaca == 0 {
+		r.broadcast(map[string]any{
+			"type":    "info",
+			"message": tr("niko_ne_prati", r.highestBidder.ime()),
+		})
+		r.startIndex = (r.startIndex + 1) % 3
+		zavrsiRuku(r)
+		return
+	}
+	if !r.rules.SmeKontru(r.kontraStatus) {
+		r.pocniIgru()
+		return
+	}
+	r.faza = fazaKontra
+	r.kontraNaRedu = (r.highestBidder.id + 1) % 3
+	if !r.players[r.kontraNaRedu].prihvatio {
+		r.kontraNaRedu = (r.kontraNaRedu + 1) % 3
+	}
+	pitanje := tr("kontra_pitanje")
+	if r.talonUzet {
+		pitanje = tr("kontra_pitanje_talon", r.highestBidder.ime())
+	}
+	r.pozoviNaKontru(pitanje)
+}
+
+func (r *Room) pozoviNaKontru(pitanje *Poruka) {
+	r.pozovi(r.players[r.kontraNaRedu], map[string]any{
+		"type":    "kontra_prompt",
+		"message": pitanje,
+	})
+	for _, pl := range r.players {
+		if pl.id != r.kontraNaRedu {
+			r.posalji(pl, map[string]any{
+				"type":    "info",
+				"message": tr("ceka_kontru"),
+			})
+		}
+	}
+}
+
+// odgovorNaKontru beleži odgovor igrača koji je na redu i pita sledećeg ili
+// počinje igru; zove se sa zaključanim r.mu
+func (r *Room) odgovorNaKontru(p *Player, daje bool) {
+	if daje && r.rules.SmeKontru(r.kontraStatus) {
+		r.kontraStatus++
+		if r.kontraStatus == 1 {
+			r.kontraBy = p.id
+		}
+		r.kontraActive = true
+		r.kontraPlayers = append(r.kontraPlayers, p.id)
+		p.kontrirao = true
+		r.broadcast(map[string]any{
+			"type":    "kontra_info",
+			"message": tr("daje_kontru", p.ime(), tr(fmt.Sprintf("kontra_%d", r.kontraStatus))),
+		})
+		if r.rules.SmeKontru(r.kontraStatus) {
+			// na kontru odgovara deklarant, na rekontru onaj ko je dao kontru
+			r.kontraNaRedu = r.highestBidder.id
+			if r.kontraStatus%2 == 0 {
+				r.kontraNaRedu = r.kontraBy
+			}
+			r.pozoviNaKontru(tr("kontra_dalje_pitanje", p.ime(),
+				tr(fmt.Sprintf("kontra_%d", r.kontraStatus)), tr(fmt.Sprintf("kontra_%d", r.kontraStatus+1))))
+			return
+		}
+	} else if r.kontraStatus == 0 {
+		// pratilac nije dao kontru; pita se drugi ako prati
+		if sledeci := (p.id + 1) % 3; sledeci != r.highestBidder.id && r.players[sledeci].prihvatio {
+			r.kontraNaRedu = sledeci
+			r.pozoviNaKontru(tr("kontra_pitanje"))
+			return
+		}
+	}
+	r.pocniIgru()
+}
+
+// pocniIgru završava dogovor o kontri: igra od 2 bez kontre po nekim
+// pravilima ne važi, inače počinje bacanje karata
+func (r *Room) pocniIgru() {
+	if r.rules.NevazecaIgra(r.highestBid, r.kontraStatus) {
+		r.broadcast(map[string]any{
+			"type":    "info",
+			"message": tr("nevazeca_igra"),
+		})
+		for _, pl := range r.players {
+			if pl.refe < r.maxRefe {
+				pl.refe++
+			}
+		}
+		r.startIndex = (r.startIndex + 1) % 3
+		dealCards(r)
+		return
+	}
+	startGame(r)
+}
+
+// odigrajKartu baca kartu igrača i javlja je stolu; zove se sa zaključanim r.mu.
+// Kraj ruke i sledeći potez su na pozivaocu.
+func odigrajKartu(r *Room, p *Player, card string) error {
+	uzeo, err := r.igra.Baci(p.id, card)
+	if err != nil {
+		return err
+	}
+	p.cards, _ = preferans.Ukloni(p.cards, card)
+	r.odigrane[card] = true
+	r.bacene = append(r.bacene, card)
+	r.broadcast(map[string]any{
+		"type":    "karta_bacena",
+		"message": tr("baca_kartu", p.ime(), card),
+		"card":    card,
+		"player":  p.id,
+	})
+	if uzeo >= 0 {
+		r.broadcast(map[string]any{
+			"type":    "stih",
+			"message": tr("nosi_stih", r.igrac(uzeo).ime()),
+			"player":  uzeo,
+			"stihovi": r.igra.Stihovi,
+		})
+	}
+	return nil
+}
+
+// javiPotez javlja ko baca sledeću kartu; igrač na potezu uz to dobija karte koje sme da baci
+func javiPotez(r *Room) {
+	msg := map[string]any{
+		"type":    "turn",
+		"message": tr("na_potezu", r.igrac(r.igra.NaPotezu).ime()),
+		"player":  r.igra.NaPotezu,
+	}
+	for _, p := range r.players {
+		if p.id == r.igra.NaPotezu {
+			r.pozovi(p, maps.Clone(msg))
+		} else {
+			r.posalji(p, msg)
+		}
+	}
+	for _, g := range r.gledaoci {
+		r.posalji(g, msg)
+	}
 }
+
 func getKontraMultiplier(r *Room) int {
 	if !r.kontraActive {
 		return 1
 	}
-	switch r.kontraStatus {
-	case 1:
-		return 2
-	case 2:
-		return 4
-	case 3:
-		return 8
-	default:
-		return 1
-	}
+	return preferans.KontraMultiplier(r.kontraStatus)
 }
 
 func handleMessage(p *Player, msg []byte) {
@@ -307,31 +675,141 @@ func handleMessage(p *Player, msg []byte) {
 	}
 	var m map[string]interface{}
 	if err := json.Unmarshal(msg, &m); err != nil {
-		log.Println("Invalid message:", err)
+		p.log().Warn("neispravna poruka", "greska", err)
+		metrika.greske.dodaj("invalid_message")
 		return
 	}
+	tip := ulazniTip(fmt.Sprint(m["type"]))
+	metrika.ulazne.dodaj(tip)
+	p.log().Debug("poruka", "tip", tip)
+	pocetak := time.Now()
+	defer func() { metrika.obrada.posmatraj(tip, time.Since(pocetak)) }()
+	mu.Lock()
 	r := rooms[p.room]
+	mu.Unlock()
+	if r == nil {
+		return
+	}
+	if debugSoba != "" && r.id == debugSoba {
+		protokol(p.log(), "ulaz", tip, msg)
+	}
+	switch m["type"] {
+	case "chat":
+		tekst, _ := m["tekst"].(string)
+		r.posaljiChat(p, tekst)
+		return
+	case "brza_poruka":
+		kod, _ := m["kod"].(string)
+		r.posaljiBrzuPoruku(p, kod)
+		return
+	case "jezik":
+		oznaka, _ := m["jezik"].(string)
+		r.mu.Lock()
+		r.promeniJezik(p, oznaka)
+		r.mu.Unlock()
+		return
+	case "stanje":
+		r.mu.Lock()
+		r.posaljiStanje(p)
+		r.mu.Unlock()
+		return
+	}
+	if p.gledalac {
+		// gledaoci samo pišu u svoj kanal
+		return
+	}
+	// pretrage zakazane ovom porukom rade tek kad se soba otključa
+	defer r.odradiPoslove()
+	// poruke jedne sobe se obrađuju jedna po jedna
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.vezbanje != nil && r.vezbanje.gotovo {
+		return
+	}
+	// svi dobijaju novo stanje tek kad odigraju i botovi, a kad čeka
+	// pretraga, tek kad se ona primeni
+	defer func() {
+		if len(r.poslovi) == 0 {
+			r.javiStanje()
+		}
+	}()
+	// botovi odigraju svoje poteze pre nego što se soba otključa
+	defer r.igrajBotove()
+	r.izvrsi(p, m)
+}
+
+// posaoVanBrave je duga pretraga: racunaj radi bez brave sobe, a primeni
+// upisuje rezultat pod bravom
+type posaoVanBrave struct {
+	racunaj, primeni func()
+}
+
+// uPozadini zakazuje pretragu koja se radi kad handleMessage otključa sobu,
+// da sto ne stoji dok ona radi; zove se sa zaključanim r.mu
+func (r *Room) uPozadini(racunaj, primeni func()) {
+	r.poslovi = append(r.poslovi, posaoVanBrave{racunaj, primeni})
+}
+
+// odradiPoslove radi zakazane pretrage bez brave, pa rezultat primenjuje kao
+// da je stigla poruka: posle primene igraju botovi i svi dobijaju stanje.
+// Zove se bez r.mu.
+func (r *Room) odradiPoslove() {
+	for {
+		r.mu.Lock()
+		if len(r.poslovi) == 0 {
+			r.mu.Unlock()
+			return
+		}
+		posao := r.poslovi[0]
+		r.poslovi = r.poslovi[1:]
+		r.mu.Unlock()
+
+		posao.racunaj()
+		r.mu.Lock()
+		posao.primeni()
+		r.igrajBotove()
+		r.javiStanje()
+		r.mu.Unlock()
+	}
+}
+
+// izvrsi obrađuje poruku igrača za stolom; zove se sa zaključanim r.mu, pa
+// istim putem igraju i botovi
+func (r *Room) izvrsi(p *Player, m map[string]interface{}) {
 	switch m["type"] {
 	case "stil_odabran":
+		// samo deklarant, i samo jednom posle biraj_stil: inače bi bilo ko mogao da uzme talon
+		if p != r.highestBidder || r.faza != fazaAdut {
+			return
+		}
 		stil, _ := m["stil"].(string)
+		if !r.ponudjeno(p, "aduti", m["stil"]) {
+			r.greska(p, tr("neispravan_adut"))
+			return
+		}
+		r.faza = fazaSkart
 		r.adut = stil
 		r.broadcast(map[string]any{
 			"type":    "adut_info",
-			"message": fmt.Sprintf("Deklarant %d bira adut: %s", p.id, stil),
-		})
-		r.talonOtkriven = true
-		r.broadcast(map[string]any{
-			"type":  "talon_info",
-			"talon": r.talon,
+			"message": tr("bira_adut", p.ime(), stil),
 		})
+		if r.rules.TalonOtkriven {
+			r.broadcast(map[string]any{
+				"type":  "talon_info",
+				"talon": r.talon,
+			})
+		}
 		p.cards = append(p.cards, r.talon...)
-		sortCards(p.cards)
-		p.conn.WriteJSON(map[string]any{
+		preferans.SortCards(p.cards)
+		r.pozovi(p, map[string]any{
 			"type":  "discard_talon",
 			"cards": p.cards,
 		})
 
 	case "odbaci_karte":
+		if p != r.highestBidder || r.faza != fazaSkart {
+			return
+		}
 		lista, _ := m["karte"].([]interface{})
 		odabrane := make(map[string]bool)
 		for _, k := range lista {
@@ -339,207 +817,356 @@ func handleMessage(p *Player, msg []byte) {
 				odabrane[ks] = true
 			}
 		}
-		novaRuka := []string{}
+		novaRuka, skart := []string{}, []string{}
 		for _, c := range p.cards {
-			if !odabrane[c] {
+			if odabrane[c] {
+				skart = append(skart, c)
+			} else {
 				novaRuka = append(novaRuka, c)
 			}
 		}
 		if len(novaRuka) != 10 {
-			p.conn.WriteJSON(map[string]any{
-				"type":    "error",
-				"message": "Moraš odbaciti tačno 2 karte!",
-			})
+			r.greska(p, tr("skart_dve"))
 			return
 		}
 		p.cards = novaRuka
-		r.cekamoKontru = 2
-		for _, pp := range r.players {
-			if pp != p {
-				pp.conn.WriteJSON(map[string]any{
-					"type":    "kontra_prompt",
-					"message": fmt.Sprintf("Da li igrač %d može da igra ili kontriraš?", p.id),
-				})
-			}
-		}
-		p.conn.WriteJSON(map[string]any{
-			"type":    "info",
-			"message": "Čekamo da protivnici odluče o kontri...",
-		})
+		r.skart = skart
+		r.pitajZaPracenje()
 	case "pass":
-		if p.passed {
-			return
-		}
-		p.passed = true
-		if r != nil {
-			r.passCount++
-		}
-		r.broadcast(map[string]any{
-			"type":    "info",
-			"message": fmt.Sprintf("Igrač %d kaže pas", p.id),
-		})
-		if r.passCount == 3 {
-			r.broadcast(map[string]any{
-				"type":    "info",
-				"message": "Svi igrači su rekli pas. Nova podela.",
-			})
-			//startGame(r)
+		r.licitiraj(p, preferans.Pas)
+
+	case "bid":
+		// vrednost je bilo koja ponuda iz your_turn: pas, broj ili igra bez talona
+		val, ok := m["value"].(float64)
+		if !ok || val != math.Trunc(val) {
+			r.greska(p, tr("neponudjena_ponuda"))
 			return
 		}
-		if r.passCount == 2 {
-			// jedan igrač je ostao — završena licitacija
-			r.auctionDone = true
-			r.potvrdaOdigravanja = true
-			r.highestBidder.conn.WriteJSON(map[string]any{
-				"type":    "potvrdi_igru",
-				"message": "Potvrdi šta igraš ili najavi veću igru.",
-			})
-			r.broadcast(map[string]any{
-				"type":    "info",
-				"message": "Čeka se potvrda deklaranta.",
-			})
+		r.licitiraj(p, int(val))
+
+	case "igra":
+		val, _ := m["value"].(string)
+		v, ok := preferans.Deklaracije[val]
+		if !ok {
+			r.greska(p, tr("neponudjena_ponuda"))
 			return
 		}
-		next := (r.currentBidIndex + 1) % 3
-		for r.players[next].passed {
-			next = (next + 1) % 3
-		}
-		r.currentBidIndex = next
-		r.players[next].conn.WriteJSON(map[string]any{
-			"type":  "your_turn",
-			"index": next,
-		})
+		r.licitiraj(p, v)
 
 	case "potvrdi_igru":
-		if p != r.highestBidder || !r.potvrdaOdigravanja {
+		if p != r.highestBidder || r.faza != fazaPotvrda {
 			return
 		}
-		adutStr, ok := m["value"].(string)
-		if !ok {
+		// adut se bira ovde samo u igri bez talona; iz talona posle biraj_stil
+		adutStr, _ := m["value"].(string)
+		var naziv any = ugovor(r.highestBid)
+		switch {
+		case len(r.adutiPotvrde()) == 0 && m["value"] != nil && m["value"] != "":
+			r.greska(p, tr("adut_se_ne_bira"))
 			return
+		case len(r.adutiPotvrde()) == 0:
+			adutStr = ""
+		case !r.ponudjeno(p, "aduti", m["value"]):
+			r.greska(p, tr("neispravan_adut"))
+			return
+		default:
+			naziv = adutStr
 		}
 		r.adut = adutStr
-		r.potvrdaOdigravanja = false
 		r.broadcast(map[string]any{
 			"type":    "info",
-			"message": fmt.Sprintf("Igrač %d potvrđuje igru: %s", p.id, adutStr),
+			"message": tr("potvrdjuje_igru", p.ime(), naziv),
 		})
 
-		if r.highestBid <= 5 {
-			// Igra se iz talona – svi vide talon, deklarant bira štil
-			r.broadcast(map[string]any{
-				"type":    "talon_info",
-				"message": "Otkriven je talon.",
-				"talon":   r.talon,
-			})
+		if preferans.SaTalonom(r.highestBid) {
+			// Igra se iz talona – deklarant bira štil, a talon vide svi ako pravila tako kažu
+			r.talonUzet = true
+			r.faza = fazaAdut
+			if r.rules.TalonOtkriven {
+				r.broadcast(map[string]any{
+					"type":    "talon_info",
+					"message": tr("talon_otkriven"),
+					"talon":   r.talon,
+				})
+			}
 
-			r.highestBidder.conn.WriteJSON(map[string]any{
+			r.pozovi(r.highestBidder, map[string]any{
 				"type":    "biraj_stil",
-				"message": "Izaberi dve karte za štil.",
+				"message": tr("biraj_stil"),
 				"cards":   r.talon,
 			})
 			return
 		}
 
-		// inače odmah pitaj za kontru
-		r.cekamoKontru = 0
-		for _, pl := range r.players {
-			if pl != r.highestBidder {
-				pl.conn.WriteJSON(map[string]any{
-					"type":    "kontra_prompt",
-					"message": "Da li daješ kontru?",
-				})
-				r.cekamoKontru++
-			}
+		// inače odmah pitaj protivnike da li prate
+		r.pitajZaPracenje()
+	case "prati":
+		if r.faza != fazaPracenje || p.id != r.pitanZaPracenje {
+			return
 		}
-	case "kontra_odgovor":
-		ox, ok := m["kontra"].(bool)
-		if !ok || r == nil {
+		if !r.ponudjeno(p, "odgovori", m["prati"]) {
+			r.greska(p, tr("neponudjen_odgovor"))
 			return
 		}
-		if ox {
-			r.kontraStatus++
-			r.kontraBy = p.id
-			r.kontraActive = true
-			r.kontraPlayers = append(r.kontraPlayers, p.id)
-			if r.kontraStatus > 3 {
-				r.kontraStatus = 3
-			}
-			r.broadcast(map[string]any{
-				"type":    "kontra_info",
-				"message": fmt.Sprintf("Igrač %d daje %s.", p.id, []string{"kontru", "rekontru", "subkontru"}[r.kontraStatus-1]),
-			})
+		r.odgovorNaPracenje(p, m["prati"].(bool))
+	case "kontra_odgovor":
+		if r.faza != fazaKontra || p.id != r.kontraNaRedu {
+			return
 		}
-		r.cekamoKontru--
-		if r.cekamoKontru == 0 {
-			startGame(r)
+		if !r.ponudjeno(p, "odgovori", m["kontra"]) {
+			r.greska(p, tr("neponudjen_odgovor"))
+			return
 		}
+		r.odgovorNaKontru(p, m["kontra"].(bool))
 	case "baci_kartu":
 		card, ok := m["card"].(string)
-		if !ok {
+		if !ok || r.igra == nil {
 			return
 		}
-		r.broadcast(map[string]any{
-			"type":    "karta_bacena",
-			"message": fmt.Sprintf("Igrač %d baca %s", p.id, card),
-			"card":    card,
-			"player":  p.id,
-		})
-
+		if r.zahtev != nil {
+			r.greska(p, tr("ceka_zahtev"))
+			return
+		}
+		if err := odigrajKartu(r, p, card); err != nil {
+			r.greska(p, porukaGreske(err))
+			return
+		}
+		if r.igra.Gotovo() {
+			zavrsiRuku(r)
+			return
+		}
+		javiPotez(r)
 		return
 
-	case "igra":
-		// Samo igrači koji još nisu licitirali mogu da kažu "igra"
-		if p.bidDeclared || p.passed {
+	case "claim":
+		stihova, ok := m["stihova"].(float64)
+		if !ok || r.igra == nil {
 			return
 		}
-		val, _ := m["value"].(string)
-		if val != "igra" && val != "betl" && val != "sans" {
+		if stihova != math.Trunc(stihova) {
+			r.greska(p, tr("zahtev_granice", 10-r.igra.Odigrano))
 			return
 		}
-		p.declaredGame = val
+		r.zahtevaj(p, int(stihova))
+
+	case "hint":
+		r.savet(p)
+
+	case "claim_odgovor":
+		prihvata, _ := m["prihvatam"].(bool)
+		r.odgovorNaZahtev(p, prihvata)
+
+	case "revans":
+		prihvata, _ := m["prihvatam"].(bool)
+		odgovorNaRevans(r, p, prihvata)
+
+	}
+}
+
+// licitiraj primenjuje ponudu igrača na potezu: preferans.Pas, broj ili igru
+// bez talona. Pravila licitacije su u preferans.Licitacija: prihvata se samo
+// ponuda iz Dozvoljene, ista koju je igrač dobio u your_turn. Zove se sa
+// zaključanim r.mu.
+func (r *Room) licitiraj(p *Player, v int) {
+	if r.faza != fazaLicitacija {
+		return
+	}
+	l := r.licitacija
+	pre := l.Najvisa
+	if err := l.Ponudi(p.id, v); err != nil {
+		r.greska(p, porukaGreske(err))
+		return
+	}
+	r.ponude = append(r.ponude, Ponuda{Mesto: p.id, Ponuda: v})
+	if l.Deklarant >= 0 {
+		r.highestBid = l.Najvisa
+		r.highestBidder = r.players[l.Deklarant]
+	}
+	switch {
+	case v == preferans.Pas:
 		r.broadcast(map[string]any{
 			"type":    "info",
-			"message": fmt.Sprintf("Igrač %d deklariše: %s", p.id, val),
+			"message": tr("pas", p.ime()),
 		})
-		// Uporedi jačinu igre i odredi pobednika licitacije
-		if r.highestBidder == nil || jačaDeklaracija(val, r.highestBidder.declaredGame) {
-			r.highestBidder = p
-			r.highestBid = map[string]int{"igra": 8, "betl": 9, "sans": 10}[val]
-		}
-		// Idi na sledećeg igrača
-		next := (r.currentBidIndex + 1) % 3
-		r.currentBidIndex = next
-		r.players[next].conn.WriteJSON(map[string]any{
-			"type":  "your_turn",
-			"index": next,
+	case v >= preferans.Igra:
+		r.broadcast(map[string]any{
+			"type":    "info",
+			"message": tr("deklarise", p.ime(), ugovor(v)),
+		})
+	default:
+		// "moje": igrač sa prednošću zadržava istu ponudu
+		poruka := tr("licitira", p.ime(), v)
+		if v == pre {
+			poruka = tr("moje", p.ime(), v)
+		}
+		r.broadcast(map[string]any{
+			"type":    "info",
+			"message": poruka,
 		})
+	}
 
+	switch {
+	case l.SviPas():
+		r.broadcast(map[string]any{
+			"type":    "info",
+			"message": tr("svi_pas"),
+		})
+		r.startIndex = (r.startIndex + 1) % 3
+		dealCards(r)
+		return
+	case l.Gotova():
+		// jedan igrač je ostao — završena licitacija
+		r.faza = fazaPotvrda
+		r.pozovi(r.highestBidder, map[string]any{
+			"type":    "potvrdi_igru",
+			"message": tr("potvrdi_igru"),
+		})
+		r.broadcast(map[string]any{
+			"type":    "info",
+			"message": tr("ceka_potvrdu"),
+		})
+		return
 	}
+	r.pozovi(r.players[l.NaPotezu], map[string]any{
+		"type":  "your_turn",
+		"index": l.NaPotezu,
+	})
 }
+
 func handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	// veza se vezuje za ulogovanog korisnika
+	user, err := userFromRequest(r)
 	if err != nil {
-		log.Println("Upgrade error:", err)
+		jsonError(w, r, http.StatusUnauthorized, tr("prijavi_se"))
 		return
 	}
+	ip := adresaKlijenta(r)
+	if !prijaviVezu(ip) {
+		metrika.greske.dodaj("ip_limit")
+		jsonError(w, r, http.StatusTooManyRequests, tr("previse_veza"))
+		return
+	}
+	defer odjaviVezu(ip)
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("websocket upgrade", "greska", err)
+		metrika.greske.dodaj("upgrade")
+		return
+	}
+	ws.SetReadLimit(int64(konfig.MaxPoruka))
+	conn := novaVeza(ws)
 	defer conn.Close()
+	// jezik poruka, npr. /ws?jezik=en; bez njega Accept-Language
+	conn.postaviJezik(jezikZahteva(r))
+
+	// pravila se biraju pri ulasku, npr. /ws?pravila=klub
+	naziv := r.URL.Query().Get("pravila")
+	if naziv == "" {
+		naziv = konfig.Pravila
+	}
+	rules, err := preferans.RulesZa(naziv)
+	if err != nil {
+		conn.WriteJSON(map[string]any{
+			"type":    "error",
+			"message": nepoznataPravila(naziv),
+		})
+		return
+	}
 
-	player := &Player{conn: conn}
-	roomID := assignToRoom(player)
-	log.Printf("Player joined %s", roomID)
+	player := &Player{conn: conn, user: user, name: user.Ime, povezan: true}
+	if gasiSe.Load() && r.URL.Query().Get("soba") == "" {
+		// dok se server gasi, vraćaju se samo igrači koji već imaju mesto
+		conn.WriteJSON(map[string]any{
+			"type":    "error",
+			"message": errGasiSe,
+		})
+		return
+	}
+	if sobaID := r.URL.Query().Get("soba"); sobaID != "" {
+		// povratak za sto ili zamena igrača, /ws?soba=room1
+		if player, err = preuzmiMesto(conn, user, sobaID); err != nil {
+			conn.WriteJSON(map[string]any{
+				"type":    "error",
+				"message": porukaGreske(err),
+			})
+			return
+		}
+		player.log().Info("igrač preuzeo mesto")
+	} else if sobaID := r.URL.Query().Get("gledaj"); sobaID != "" {
+		// gledalac ulazi u postojeću sobu, /ws?gledaj=room1
+		if err := gledaj(player, sobaID); err != nil {
+			conn.WriteJSON(map[string]any{
+				"type":    "error",
+				"message": porukaGreske(err),
+			})
+			return
+		}
+		player.log().Info("gledalac ušao")
+	} else if id := r.URL.Query().Get("turnir"); id != "" {
+		// turnirski igrač seda za sto iz rasporeda, /ws?turnir=t1
+		t := turniri.get(id)
+		if t == nil {
+			err = errNemaTurnira
+		} else {
+			err = t.sedi(player)
+		}
+		if err != nil {
+			conn.WriteJSON(map[string]any{
+				"type":    "error",
+				"message": porukaGreske(err),
+			})
+			return
+		}
+		player.log().Info("igrač seo za turnirski sto", "turnir", id)
+	} else {
+		// soba za učenje sa savetima, /ws?trening=1
+		assignToRoom(player, rules, r.URL.Query().Get("trening") == "1")
+		player.log().Info("igrač seo za sto")
+	}
 
+	c := noviCuvar(ip)
 	for {
 		_, msg, err := conn.ReadMessage()
 		if err != nil {
-			log.Println("Read error:", err)
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				metrika.greske.dodaj("read")
+			}
+			player.log().Info("veza prekinuta", "greska", err)
+			break
+		}
+		obradi, prekini := c.propusti(conn, msg)
+		if prekini {
+			player.log().Warn("veza prekinuta zbog prekršaja", "ip", ip)
 			break
 		}
-		handleMessage(player, msg)
+		if obradi {
+			handleMessage(player, msg)
+		}
 	}
+	odjavi(player, conn)
 }
 
-func jačaDeklaracija(a, b string) bool {
-	redosled := map[string]int{"igra": 1, "betl": 2, "sans": 3}
-	return redosled[a] > redosled[b]
+// odjavi beleži da je veza igrača prekinuta, osim ako je mesto već preuzela nova veza
+func odjavi(p *Player, conn *veza) {
+	mu.Lock()
+	room := rooms[p.room]
+	mu.Unlock()
+	if room == nil {
+		p.povezan = false
+		return
+	}
+	room.mu.Lock()
+	if p.gledalac {
+		room.gledaoci = slices.DeleteFunc(room.gledaoci, func(g *Player) bool { return g == p })
+	}
+	vezba := false
+	if p.conn == conn {
+		p.povezan = false
+		// za sto za vežbanje nema ko drugi da se vrati
+		vezba = room.vezbanje != nil
+	}
+	room.mu.Unlock()
+	if vezba {
+		ukloniSobu(room)
+	}
 }
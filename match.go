@@ -0,0 +1,215 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"multiplayer-game/preferans"
+)
+
+// Match prati meč koji se igra u sobi: svako počinje sa bulom iz pravila, meč se
+// završava kad zbir svih bula padne na nulu, a onda se bule i supe svode na saldo.
+type Match struct {
+	room     *Room
+	bule     [3]int
+	supe     [3][3]int // supe[i][j]: supe koje je igrač i upisao na igrača j
+	podele   int
+	gotov    bool
+	revans   map[int]bool // ko je prihvatio revanš
+	istorija []preferans.Obracun
+	// ID naloga po mestu, da istorija prati osobu a ne mesto
+	korisnici [3]int
+}
+
+func newMatch(r *Room) *Match {
+	m := &Match{room: r, revans: map[int]bool{}}
+	for _, p := range r.players {
+		// refe se ne prenose iz prethodnog meča
+		p.refe = 0
+		if p.user != nil {
+			m.korisnici[p.id] = p.user.ID
+		}
+	}
+	for i := range m.bule {
+		m.bule[i] = r.rules.PocetnaBula
+	}
+	return m
+}
+
+// upisi dodaje ishod jedne podele i javlja da li je meč time završen
+func (m *Match) upisi(o preferans.Obracun) bool {
+	m.podele++
+	m.istorija = append(m.istorija, o)
+	for i := range m.bule {
+		m.bule[i] += o.Bule[i]
+		m.supe[i][o.Deklarant] += o.Supe[i]
+	}
+	m.gotov = m.bule[0]+m.bule[1]+m.bule[2] <= 0
+	return m.gotov
+}
+
+// stanje je ono što se šalje klijentima posle svake podele
+func (m *Match) stanje() map[string]any {
+	refe := make([]int, len(m.room.players))
+	for _, p := range m.room.players {
+		refe[p.id] = p.refe
+	}
+	return map[string]any{
+		"bule":      m.bule,
+		"supe":      m.supe,
+		"refe":      refe,
+		"podela":    m.podele,
+		"imena":     m.room.imena(),
+		"korisnici": m.korisnici,
+	}
+}
+
+// zavrsiRuku upisuje odigranu ruku u meč i deli sledeću, ili objavljuje kraj meča
+func zavrsiRuku(r *Room) {
+	var pratili, kontrirali [3]bool
+	for _, p := range r.players {
+		pratili[p.id] = p.prihvatio
+		kontrirali[p.id] = p.kontrirao
+	}
+	// bez igre (niko ne prati) niko nije uzeo štih
+	var stihovi [3]int
+	if r.igra != nil {
+		stihovi = r.igra.Stihovi
+	}
+	// otvoren refe deklaranta duplira ovu igru i time se zatvara
+	refe := r.highestBidder.refe > 0
+	if refe {
+		r.highestBidder.refe--
+	}
+	o := r.rules.Obracunaj(r.highestBid, r.highestBidder.id, pratili, stihovi, r.kontraStatus, refe)
+	r.igra = nil
+	r.faza = fazaCekanje
+	if r.vezbanje != nil {
+		// vežba se ne upisuje ni u statistiku ni u bule
+		r.zavrsiVezbanje(&o)
+		return
+	}
+	metrika.ruke.Add(1)
+	if err := zapisi.upisiRuku(ZapisRuke{
+		Vreme:      time.Now(),
+		Pravila:    r.rules.Naziv,
+		Igraci:     r.match.korisnici,
+		Deklarant:  o.Deklarant,
+		Ponuda:     o.Ponuda,
+		Kontra:     r.kontraStatus,
+		Kontrirali: kontrirali,
+		Pratili:    pratili,
+		Prosao:     o.Prosao,
+		Stihovi:    o.Stihovi,
+		Bule:       o.Bule,
+		Zahtev:     r.zapisZahteva,
+		Saveti:     r.saveti,
+		Podela:     r.podeljeno,
+		Prvi:       r.prvi,
+		Licitacija: r.ponude,
+		Adut:       r.adut,
+		Skart:      r.skart,
+		Bacene:     r.bacene,
+	}); err != nil {
+		r.log().Error("upis statistike", "greska", err)
+	}
+
+	ishod := "obracun_pao"
+	if o.Prosao {
+		ishod = "obracun_prosao"
+	}
+	msg := r.match.stanje()
+	msg["type"] = "obracun"
+	msg["message"] = tr(ishod, r.igrac(o.Deklarant).ime(), o.Stihovi)
+	msg["obracun"] = o
+	if r.trening {
+		msg["saveti"] = r.saveti
+	}
+	r.broadcast(msg)
+
+	if r.turnir != nil {
+		// na turniru se igra zadati broj podela, bez obzira na bule
+		r.turnir.upisi(o)
+		r.match.upisi(o)
+		dealCards(r)
+		return
+	}
+	if !r.match.upisi(o) {
+		dealCards(r)
+		return
+	}
+	r.faza = fazaKrajMeca
+	saldo := preferans.KonacniSaldo(r.match.bule, r.match.supe)
+	if err := zapisi.upisiMec(ZapisMeca{
+		Vreme:       time.Now(),
+		Pravila:     r.rules.Naziv,
+		Igraci:      r.match.korisnici,
+		PocetnaBula: r.rules.PocetnaBula,
+		Bule:        r.match.bule,
+		Saldo:       saldo,
+		Podela:      r.match.podele,
+	}); err != nil {
+		r.log().Error("upis statistike", "greska", err)
+	}
+	if r.match.korisnici[0] != 0 && r.match.korisnici[1] != 0 && r.match.korisnici[2] != 0 {
+		if err := rejtinzi.upisi(r.match.korisnici, saldo); err != nil {
+			r.log().Error("upis rejtinga", "greska", err)
+		}
+	}
+	r.broadcast(map[string]any{
+		"type":      "kraj_meca",
+		"message":   tr("kraj_meca"),
+		"bule":      r.match.bule,
+		"supe":      r.match.supe,
+		"saldo":     saldo,
+		"podela":    r.match.podele,
+		"imena":     r.imena(),
+		"korisnici": r.match.korisnici,
+		"rejting":   r.rejtingStola(),
+	})
+	for _, p := range r.players {
+		r.pozovi(p, map[string]any{
+			"type":    "revans_prompt",
+			"message": tr("revans_pitanje"),
+		})
+	}
+}
+
+// odgovorNaRevans počinje novi meč kad sva tri igrača prihvate
+func odgovorNaRevans(r *Room, p *Player, prihvata bool) {
+	if r.match == nil || !r.match.gotov {
+		return
+	}
+	if !prihvata {
+		// bez revanša meč je gotov: veze se zatvaraju, a sto se briše posle otključavanja
+		r.match = nil
+		r.faza = fazaCekanje
+		r.broadcast(map[string]any{
+			"type":    "obavestenje",
+			"message": tr("revans_odbija", p.ime()),
+		})
+		for _, pl := range append(append([]*Player{}, r.players...), r.gledaoci...) {
+			pl.povezan = false
+			pl.conn.zatvoriSa(websocket.CloseNormalClosure, "meč je završen")
+		}
+		r.uPozadini(func() { ukloniSobu(r) }, func() {})
+		return
+	}
+	r.match.revans[p.id] = true
+	r.broadcast(map[string]any{
+		"type":    "info",
+		"message": tr("revans_prihvata", p.ime()),
+	})
+	if len(r.match.revans) < 3 {
+		return
+	}
+	r.match = newMatch(r)
+	r.dealCount = 0
+	r.broadcast(map[string]any{
+		"type":    "novi_mec",
+		"message": tr("revans_pocinje"),
+		"bule":    r.match.bule,
+	})
+	dealCards(r)
+}
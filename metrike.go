@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ==== Metrike ====
+// /metrics u Prometheus tekstualnom formatu, bez spoljnih biblioteka.
+var metrika = struct {
+	veze               atomic.Int64
+	ruke               atomic.Int64
+	ponovnaPovezivanja atomic.Int64
+	ulazne             *brojacPoTipu
+	izlazne            *brojacPoTipu
+	greske             *brojacPoTipu
+	obrada             *histogram
+}{
+	ulazne:  &brojacPoTipu{vrednosti: map[string]int64{}},
+	izlazne: &brojacPoTipu{vrednosti: map[string]int64{}},
+	greske:  &brojacPoTipu{vrednosti: map[string]int64{}},
+	obrada:  &histogram{granice: []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1}, poTipu: map[string]*serija{}},
+}
+
+// ulazniTipovi su poruke koje server prima; ostalo se broji kao "nepoznat" da klijent
+// ne bi mogao da napravi proizvoljno mnogo serija
+var ulazniTipovi = map[string]bool{
+	"pass": true, "bid": true, "igra": true, "potvrdi_igru": true, "stil_odabran": true,
+	"odbaci_karte": true, "prati": true, "kontra_odgovor": true, "baci_kartu": true, "revans": true,
+	"chat": true, "brza_poruka": true, "claim": true, "claim_odgovor": true, "hint": true,
+	"jezik": true, "stanje": true,
+}
+
+func ulazniTip(tip string) string {
+	if ulazniTipovi[tip] {
+		return tip
+	}
+	return "nepoznat"
+}
+
+type brojacPoTipu struct {
+	mu        sync.Mutex
+	vrednosti map[string]int64
+}
+
+func (b *brojacPoTipu) dodaj(tip string) {
+	b.mu.Lock()
+	b.vrednosti[tip]++
+	b.mu.Unlock()
+}
+
+// vrednost je trenutni broj za jedan tip
+func (b *brojacPoTipu) vrednost(tip string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.vrednosti[tip]
+}
+
+func (b *brojacPoTipu) pisi(w *strings.Builder, ime, labela string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, k := range sortiraniKljucevi(b.vrednosti) {
+		fmt.Fprintf(w, "%s{%s=%q} %d\n", ime, labela, k, b.vrednosti[k])
+	}
+}
+
+type serija struct {
+	kofe   []int64 // kumulativno po granicama
+	zbir   float64
+	ukupno int64
+}
+
+type histogram struct {
+	mu      sync.Mutex
+	granice []float64
+	poTipu  map[string]*serija
+}
+
+func (h *histogram) posmatraj(tip string, d time.Duration) {
+	s := d.Seconds()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sr, ok := h.poTipu[tip]
+	if !ok {
+		sr = &serija{kofe: make([]int64, len(h.granice))}
+		h.poTipu[tip] = sr
+	}
+	for i, g := range h.granice {
+		if s <= g {
+			sr.kofe[i]++
+		}
+	}
+	sr.zbir += s
+	sr.ukupno++
+}
+
+func (h *histogram) pisi(w *strings.Builder, ime string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, tip := range sortiraniKljucevi(h.poTipu) {
+		sr := h.poTipu[tip]
+		for i, g := range h.granice {
+			fmt.Fprintf(w, "%s_bucket{type=%q,le=\"%g\"} %d\n", ime, tip, g, sr.kofe[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{type=%q,le=\"+Inf\"} %d\n", ime, tip, sr.ukupno)
+		fmt.Fprintf(w, "%s_sum{type=%q} %g\n", ime, tip, sr.zbir)
+		fmt.Fprintf(w, "%s_count{type=%q} %d\n", ime, tip, sr.ukupno)
+	}
+}
+
+func sortiraniKljucevi[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func zaglavlje(w *strings.Builder, ime, tip, opis string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", ime, opis, ime, tip)
+}
+
+// GET /metrics
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+
+	zaglavlje(&b, "wspref_connections_active", "gauge", "Otvorene websocket veze.")
+	fmt.Fprintf(&b, "wspref_connections_active %d\n", metrika.veze.Load())
+
+	faze := map[string]int{}
+	mu.Lock()
+	for _, room := range rooms {
+		room.mu.Lock()
+		faze[room.nazivFaze()]++
+		room.mu.Unlock()
+	}
+	mu.Unlock()
+	zaglavlje(&b, "wspref_rooms", "gauge", "Sobe po fazi podele.")
+	for _, f := range sortiraniKljucevi(faze) {
+		fmt.Fprintf(&b, "wspref_rooms{faza=%q} %d\n", f, faze[f])
+	}
+
+	zaglavlje(&b, "wspref_hands_completed_total", "counter", "Odigrane i obračunate ruke.")
+	fmt.Fprintf(&b, "wspref_hands_completed_total %d\n", metrika.ruke.Load())
+
+	zaglavlje(&b, "wspref_messages_in_total", "counter", "Primljene poruke po tipu.")
+	metrika.ulazne.pisi(&b, "wspref_messages_in_total", "type")
+	zaglavlje(&b, "wspref_messages_out_total", "counter", "Poslate poruke po tipu.")
+	metrika.izlazne.pisi(&b, "wspref_messages_out_total", "type")
+
+	zaglavlje(&b, "wspref_handler_duration_seconds", "histogram", "Trajanje obrade primljene poruke.")
+	metrika.obrada.pisi(&b, "wspref_handler_duration_seconds")
+
+	zbir, najveca := dubinaRedova()
+	zaglavlje(&b, "wspref_write_queue_depth", "gauge", "Poruke koje čekaju slanje, zbir po svim vezama.")
+	fmt.Fprintf(&b, "wspref_write_queue_depth %d\n", zbir)
+	zaglavlje(&b, "wspref_write_queue_depth_max", "gauge", "Najduži red za slanje jedne veze.")
+	fmt.Fprintf(&b, "wspref_write_queue_depth_max %d\n", najveca)
+
+	zaglavlje(&b, "wspref_reconnects_total", "counter", "Povratci igrača na svoje mesto posle prekida veze.")
+	fmt.Fprintf(&b, "wspref_reconnects_total %d\n", metrika.ponovnaPovezivanja.Load())
+
+	zaglavlje(&b, "wspref_errors_total", "counter", "Greške po vrsti.")
+	metrika.greske.pisi(&b, "wspref_errors_total", "kind")
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
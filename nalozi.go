@@ -0,0 +1,342 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ==== Nalozi ====
+type User struct {
+	ID         int       `json:"id"`
+	Ime        string    `json:"ime"`
+	Lozinka    []byte    `json:"lozinka"` // bcrypt heš
+	Napravljen time.Time `json:"napravljen"`
+}
+
+// userStore čuva naloge u JSON fajlu; ceo fajl se prepisuje pri svakoj izmeni
+type userStore struct {
+	mu     sync.Mutex
+	path   string
+	users  map[string]*User // po imenu (mala slova)
+	byID   map[int]*User
+	nextID int
+}
+
+const (
+	sessionCookie   = "wspref_sesija"
+	sessionTrajanje = 30 * 24 * time.Hour
+)
+
+var (
+	korisniciPutanja = "korisnici.json"
+	korisnici        *userStore
+	// sessionKey potpisuje kolačiće; ako nije zadat, pravi se nasumičan pa sesije ne preživljavaju restart
+	sessionKey []byte
+
+	errPostoji    = tr("ime_zauzeto")
+	errPogresno   = tr("pogresna_prijava")
+	errLosaSesija = tr("losa_sesija")
+	errLoseIme    = tr("lose_ime")
+	errKratkaLoz  = tr("kratka_lozinka")
+	errDugaLoz    = tr("duga_lozinka")
+	errPrijave    = tr("previse_prijava")
+)
+
+func loadUsers(path string) (*userStore, error) {
+	s := &userStore{path: path, users: map[string]*User{}, byID: map[int]*User{}, nextID: 1}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lista []*User
+	if err := json.Unmarshal(data, &lista); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, u := range lista {
+		s.users[strings.ToLower(u.Ime)] = u
+		s.byID[u.ID] = u
+		if u.ID >= s.nextID {
+			s.nextID = u.ID + 1
+		}
+	}
+	return s, nil
+}
+
+// save mora da se zove sa zaključanim s.mu
+func (s *userStore) save() error {
+	lista := make([]*User, 0, len(s.byID))
+	for id := 1; id < s.nextID; id++ {
+		if u, ok := s.byID[id]; ok {
+			lista = append(lista, u)
+		}
+	}
+	data, err := json.MarshalIndent(lista, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *userStore) register(ime, lozinka string) (*User, error) {
+	ime = strings.TrimSpace(ime)
+	if n := utf8.RuneCountInString(ime); n < 3 || n > 20 {
+		return nil, errLoseIme
+	}
+	if len(lozinka) < 8 {
+		return nil, errKratkaLoz
+	}
+	if len(lozinka) > 72 {
+		// bcrypt ne prima duže lozinke
+		return nil, errDugaLoz
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(lozinka), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[strings.ToLower(ime)]; ok {
+		return nil, errPostoji
+	}
+	u := &User{ID: s.nextID, Ime: ime, Lozinka: hash, Napravljen: time.Now()}
+	s.nextID++
+	s.users[strings.ToLower(ime)] = u
+	s.byID[u.ID] = u
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *userStore) login(ime, lozinka string) (*User, error) {
+	s.mu.Lock()
+	u, ok := s.users[strings.ToLower(strings.TrimSpace(ime))]
+	s.mu.Unlock()
+	if !ok {
+		return nil, errPogresno
+	}
+	if bcrypt.CompareHashAndPassword(u.Lozinka, []byte(lozinka)) != nil {
+		return nil, errPogresno
+	}
+	return u, nil
+}
+
+func (s *userStore) poImenu(ime string) *User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.users[strings.ToLower(strings.TrimSpace(ime))]
+}
+
+func (s *userStore) get(id int) *User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.byID[id]
+}
+
+// ==== Sesije ====
+// Kolačić je "id.istek.potpis", potpis je HMAC-SHA256 prva dva dela.
+func potpisi(payload string) string {
+	mac := hmac.New(sha256.New, sessionKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func setSession(w http.ResponseWriter, r *http.Request, u *User) {
+	istek := time.Now().Add(sessionTrajanje)
+	payload := fmt.Sprintf("%d.%d", u.ID, istek.Unix())
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    payload + "." + potpisi(payload),
+		Path:     "/",
+		Expires:  istek,
+		HttpOnly: true,
+		Secure:   prekoHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// userFromRequest vraća ulogovanog korisnika iz potpisanog kolačića
+func userFromRequest(r *http.Request) (*User, error) {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil, errLosaSesija
+	}
+	delovi := strings.Split(c.Value, ".")
+	if len(delovi) != 3 {
+		return nil, errLosaSesija
+	}
+	payload := delovi[0] + "." + delovi[1]
+	if !hmac.Equal([]byte(potpisi(payload)), []byte(delovi[2])) {
+		return nil, errLosaSesija
+	}
+	istek, err := strconv.ParseInt(delovi[1], 10, 64)
+	if err != nil || time.Now().Unix() > istek {
+		return nil, errLosaSesija
+	}
+	id, err := strconv.Atoi(delovi[0])
+	if err != nil {
+		return nil, errLosaSesija
+	}
+	u := korisnici.get(id)
+	if u == nil {
+		return nil, errLosaSesija
+	}
+	return u, nil
+}
+
+func initSessions() {
+	if konfig.SessionKey != "" {
+		sessionKey = []byte(konfig.SessionKey)
+		return
+	}
+	sessionKey = make([]byte, 32)
+	if _, err := rand.Read(sessionKey); err != nil {
+		log.Fatal(err)
+	}
+	slog.Warn("session-key nije zadat — sesije važe samo dok server radi")
+}
+
+// ==== Ograničenje prijava ====
+// Svaka IP adresa ima kofu od konfig.PrijavaUMinutu pokušaja prijave ili
+// registracije koja se puni istom brzinom, da se lozinke ne bi pogađale i da
+// bcrypt ne bi zauzeo procesor. Pune kofe se brišu kad ih se nakupi mnogo.
+var (
+	prijaveMu sync.Mutex
+	prijave   = map[string]*kofa{}
+)
+
+func dozvoliPrijavu(ip string) bool {
+	prijaveMu.Lock()
+	defer prijaveMu.Unlock()
+	k := prijave[ip]
+	if k == nil {
+		if len(prijave) >= 10000 {
+			for a, s := range prijave {
+				if s.puna() {
+					delete(prijave, a)
+				}
+			}
+		}
+		n := float64(konfig.PrijavaUMinutu)
+		k = &kofa{zetoni: n, brzina: n / 60, kapacitet: n, poslednje: time.Now()}
+		prijave[ip] = k
+	}
+	return k.uzmi()
+}
+
+// ==== HTTP ====
+type kredencijali struct {
+	Ime     string `json:"ime"`
+	Lozinka string `json:"lozinka"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// jsonError šalje grešku na jeziku zahteva; greška iz kataloga nosi i "kod" i
+// "param", kao poruke kroz websocket
+func jsonError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	out := map[string]any{"error": err.Error()}
+	if p, ok := porukaGreske(err).(*Poruka); ok {
+		out = prevedi(map[string]any{"message": p}, jezikZahteva(r))
+		out["error"] = out["message"]
+		delete(out, "message")
+	}
+	writeJSON(w, status, out)
+}
+
+func citajKredencijale(w http.ResponseWriter, r *http.Request) (kredencijali, bool) {
+	var k kredencijali
+	if r.Method != http.MethodPost {
+		jsonError(w, r, http.StatusMethodNotAllowed, tr("ocekuje_post"))
+		return k, false
+	}
+	if !dozvoliPrijavu(adresaKlijenta(r)) {
+		metrika.greske.dodaj("login_throttled")
+		w.Header().Set("Retry-After", "60")
+		jsonError(w, r, http.StatusTooManyRequests, errPrijave)
+		return k, false
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&k); err != nil {
+		jsonError(w, r, http.StatusBadRequest, tr("neispravan_zahtev"))
+		return k, false
+	}
+	return k, true
+}
+
+func handleRegister(w http.ResponseWriter, r *http.Request) {
+	k, ok := citajKredencijale(w, r)
+	if !ok {
+		return
+	}
+	u, err := korisnici.register(k.Ime, k.Lozinka)
+	switch {
+	case errors.Is(err, errPostoji):
+		jsonError(w, r, http.StatusConflict, err)
+		return
+	case errors.Is(err, errLoseIme), errors.Is(err, errKratkaLoz), errors.Is(err, errDugaLoz):
+		jsonError(w, r, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		slog.Error("registracija", "ime", k.Ime, "greska", err)
+		jsonError(w, r, http.StatusInternalServerError, tr("greska_servera"))
+		return
+	}
+	setSession(w, r, u)
+	writeJSON(w, http.StatusCreated, map[string]any{"id": u.ID, "ime": u.Ime})
+}
+
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	k, ok := citajKredencijale(w, r)
+	if !ok {
+		return
+	}
+	u, err := korisnici.login(k.Ime, k.Lozinka)
+	if err != nil {
+		jsonError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	setSession(w, r, u)
+	writeJSON(w, http.StatusOK, map[string]any{"id": u.ID, "ime": u.Ime})
+}
+
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1, Secure: prekoHTTPS(r)})
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+func handleMe(w http.ResponseWriter, r *http.Request) {
+	u, err := userFromRequest(r)
+	if err != nil {
+		jsonError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"id": u.ID, "ime": u.Ime})
+}
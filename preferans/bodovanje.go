@@ -0,0 +1,127 @@
+package preferans
+
+// Obracun je ishod jedne podele. Bule se upisuju deklarantu i pratiocima koji
+// padnu (pozitivno je loše, prolaz smanjuje bulu), a supe pratiocima za svaki uzeti štih.
+type Obracun struct {
+	Ponuda    int
+	Deklarant int
+	Refe      bool // deklarant je imao otvoren refe, pa je igra vredela duplo
+	Prosao    bool
+	Stihovi   [3]int
+	Bule      [3]int
+	Supe      [3]int
+}
+
+// Obracunaj računa bule i supe za odigranu (ili neodigranu, ako niko ne prati)
+// igru. Kad deklarant ima otvoren refe, igra mu vredi duplo i u prolazu i u padu,
+// a duplo se računaju i supe pratilaca.
+func (r Rules) Obracunaj(ponuda, deklarant int, pratili [3]bool, stihovi [3]int, kontraStatus int, refe bool) Obracun {
+	o := Obracun{Ponuda: ponuda, Deklarant: deklarant, Refe: refe, Stihovi: stihovi}
+	v := r.Vrednost(ponuda, kontraStatus)
+	if refe {
+		v *= 2
+	}
+
+	brojPratilaca := 0
+	for i, p := range pratili {
+		if p && i != deklarant {
+			brojPratilaca++
+		}
+	}
+	// niko ne prati — deklarant prolazi bez igranja
+	if brojPratilaca == 0 {
+		o.Prosao = true
+		o.Bule[deklarant] = -v
+		return o
+	}
+
+	o.Prosao = Prosao(ponuda, stihovi[deklarant])
+	if o.Prosao {
+		o.Bule[deklarant] = -v
+	} else {
+		o.Bule[deklarant] = v
+	}
+	if ponuda == Betl {
+		return o
+	}
+
+	// supe: pratilac dobija vrednost ponude za svaki uzeti štih. Kad prati samo
+	// jedan, on igra i za drugog branioca pa mu se (po pravilima) računaju i njegovi štihovi.
+	po := v / 2 * r.SupaPoStihu
+	uzeli := stihovi
+	if brojPratilaca == 1 && r.SamPratiZaOba {
+		for i := range pratili {
+			if i != deklarant && !pratili[i] {
+				uzeli[3-deklarant-i] += stihovi[i]
+				uzeli[i] = 0
+			}
+		}
+	}
+	ukupno := 0
+	for i := range pratili {
+		if i == deklarant || !pratili[i] {
+			continue
+		}
+		o.Supe[i] = uzeli[i] * po
+		ukupno += uzeli[i]
+	}
+	// pratioci moraju da uzmu svoje štihove; ako zajedno podbace, pada onaj ko nije
+	// uzeo svoje. Po nekim pravilima pratilac pada i kad drugi nadoknadi.
+	potrebno := r.PratilacMora * brojPratilaca
+	if ukupno < potrebno || r.PratilacPadaSam {
+		for i := range pratili {
+			if i != deklarant && pratili[i] && uzeli[i] < r.PratilacMora {
+				o.Bule[i] = v
+			}
+		}
+	}
+	return o
+}
+
+// KonacniSaldo pretvara bule i supe na kraju meča u saldo čiji je zbir nula.
+// supe[i][j] su supe koje je igrač i upisao na igrača j; bula vredi deset supa.
+// Razlika do prosečne bule se zaokružuje na najbližu supu. Svi udeli imaju isti
+// ostatak u trećinama, pa posle zaokruživanja zbiru fali ili preteče tačno jedna
+// supa; nju dobija igrač sa najmanjim saldom, odnosno plaća igrač sa najvećim,
+// a ne neko mesto za stolom.
+func KonacniSaldo(bule [3]int, supe [3][3]int) [3]int {
+	var saldo [3]int
+	zbirBula := bule[0] + bule[1] + bule[2]
+	for i := range saldo {
+		for j := range saldo {
+			saldo[i] += supe[i][j] - supe[j][i]
+		}
+		// 10*(prosek - bula); računa se u trećinama da bi ostalo celobrojno
+		saldo[i] += naTrecine(10*zbirBula - 30*bule[i])
+	}
+	switch visak := saldo[0] + saldo[1] + saldo[2]; {
+	case visak > 0:
+		saldo[najveci(saldo, 1)] -= visak
+	case visak < 0:
+		saldo[najveci(saldo, -1)] -= visak
+	}
+	return saldo
+}
+
+// naTrecine deli sa tri i zaokružuje na najbliži ceo broj
+func naTrecine(a int) int {
+	q, ost := a/3, a%3
+	switch ost {
+	case 2:
+		q++
+	case -2:
+		q--
+	}
+	return q
+}
+
+// najveci vraća mesto sa najvećim znak*saldo; pri jednakosti prvo takvo mesto
+func najveci(saldo [3]int, znak int) int {
+	m := 0
+	for i := range saldo {
+		if znak*saldo[i] > znak*saldo[m] {
+			m = i
+		}
+	}
+	return m
+}
@@ -0,0 +1,104 @@
+// Package preferans sadrži pravila igre izdvojena iz servera: špil, licitaciju,
+// ugovore, štihove i obračun. Koriste ga server i alati iz cmd/.
+package preferans
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Deck je špil od 32 karte
+var Deck = []string{
+	"7♠", "8♠", "9♠", "10♠", "J♠", "Q♠", "K♠", "A♠",
+	"7♦", "8♦", "9♦", "10♦", "J♦", "Q♦", "K♦", "A♦",
+	"7♥", "8♥", "9♥", "10♥", "J♥", "Q♥", "K♥", "A♥",
+	"7♣", "8♣", "9♣", "10♣", "J♣", "Q♣", "K♣", "A♣",
+}
+
+// Suits su boje redom kojim se karte sortiraju
+var Suits = []rune{'♠', '♦', '♥', '♣'}
+
+var RankOrder = map[string]int{
+	"7": 1, "8": 2, "9": 3, "10": 4, "J": 5, "Q": 6, "K": 7, "A": 8,
+}
+
+var SuitOrder = map[rune]int{
+	'♠': 1, '♦': 2, '♥': 3, '♣': 4,
+}
+
+func SortCards(cards []string) {
+	sort.Slice(cards, func(i, j int) bool {
+		r1, s1 := ParseCard(cards[i])
+		r2, s2 := ParseCard(cards[j])
+		if SuitOrder[s1] != SuitOrder[s2] {
+			return SuitOrder[s1] < SuitOrder[s2]
+		}
+		return RankOrder[r1] < RankOrder[r2]
+	})
+}
+
+func ParseCard(card string) (rank string, suit rune) {
+	runes := []rune(card)
+	if len(runes) == 3 {
+		return string(runes[0:2]), runes[2]
+	}
+	if len(runes) < 2 {
+		return card, 0
+	}
+	return string(runes[0]), runes[1]
+}
+
+// Suit vraća boju karte
+func Suit(card string) rune {
+	_, s := ParseCard(card)
+	return s
+}
+
+// ShuffleCards vraća promešanu kopiju karata. Ako je rnd nil, koristi se seme iz trenutnog vremena.
+func ShuffleCards(cards []string, rnd *rand.Rand) []string {
+	if rnd == nil {
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	shuffled := make([]string, len(cards))
+	perm := rnd.Perm(len(cards))
+	for i, v := range perm {
+		shuffled[v] = cards[i]
+	}
+	return shuffled
+}
+
+// Podeli deli promešan špil: po 10 karata svakom igraču, poslednje dve idu u talon
+func Podeli(shuffled []string) (ruke [3][]string, talon []string) {
+	for i := range ruke {
+		ruke[i] = append([]string{}, shuffled[i*10:(i+1)*10]...)
+		SortCards(ruke[i])
+	}
+	talon = append([]string{}, shuffled[30:32]...)
+	return ruke, talon
+}
+
+// Ukloni vraća ruku bez datih karata i da li su sve karte nađene
+func Ukloni(ruka []string, karte ...string) ([]string, bool) {
+	odabrane := make(map[string]bool, len(karte))
+	for _, k := range karte {
+		odabrane[k] = true
+	}
+	nova := make([]string, 0, len(ruka))
+	for _, c := range ruka {
+		if !odabrane[c] {
+			nova = append(nova, c)
+		}
+	}
+	return nova, len(ruka)-len(nova) == len(odabrane)
+}
+
+// Sadrzi proverava da li je karta u ruci
+func Sadrzi(ruka []string, card string) bool {
+	for _, c := range ruka {
+		if c == card {
+			return true
+		}
+	}
+	return false
+}
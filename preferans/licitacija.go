@@ -0,0 +1,111 @@
+package preferans
+
+import "errors"
+
+var (
+	ErrNijeNaRedu       = errors.New("nije tvoj red za licitaciju")
+	ErrVecPasirao       = errors.New("već si rekao pas")
+	ErrPreniskaPonuda   = errors.New("ponuda mora biti veća od trenutne")
+	ErrNeispravnaPonuda = errors.New("neispravna ponuda")
+	ErrLicitacijaGotova = errors.New("licitacija je završena")
+)
+
+// Licitacija prati tok licitacije za jednu podelu. Pas je konačan; igra, betl i
+// sans mogu da najave samo igrači koji još nisu licitirali brojem.
+type Licitacija struct {
+	Prvi       int
+	Pravila    Rules
+	NaPotezu   int
+	Najvisa    int
+	Deklarant  int // -1 dok niko nije licitirao
+	Pasirao    [3]bool
+	Licitirao  [3]bool
+	Najava     [3]string // "igra", "betl", "sans" ako je igrač najavio igru
+	BrojPasova int
+}
+
+func NovaLicitacija(prvi int, pravila Rules) *Licitacija {
+	return &Licitacija{Prvi: prvi, Pravila: pravila, NaPotezu: prvi, Deklarant: -1}
+}
+
+// Gotova je kad su dva igrača rekla pas a treći je licitirao, ili kad su svi rekli pas
+func (l *Licitacija) Gotova() bool {
+	return l.BrojPasova == 3 || (l.BrojPasova == 2 && l.Deklarant >= 0)
+}
+
+// SviPas je podela u kojoj niko nije licitirao; deli se ponovo
+func (l *Licitacija) SviPas() bool {
+	return l.BrojPasova == 3
+}
+
+// Dozvoljene vraća sve ponude koje igrač sme da da (Pas je uvek dozvoljen)
+func (l *Licitacija) Dozvoljene(igrac int) []int {
+	if l.Gotova() || igrac != l.NaPotezu || l.Pasirao[igrac] {
+		return nil
+	}
+	out := []int{Pas}
+	if l.Najvisa >= MinBroj && l.Najvisa <= MaxBroj && l.Pravila.SmeMoje(igrac, l.Deklarant, l.Prvi) {
+		out = append(out, l.Najvisa) // moje
+	}
+	if l.Najvisa < Igra {
+		for v := max(l.Najvisa+1, MinBroj); v <= MaxBroj; v++ {
+			out = append(out, v)
+		}
+	}
+	if !l.Licitirao[igrac] {
+		for _, v := range []int{Igra, Betl, Sans} {
+			if v > l.Najvisa {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// Ponudi primenjuje ponudu igrača i pomera potez na sledećeg koji nije rekao pas
+func (l *Licitacija) Ponudi(igrac, ponuda int) error {
+	if l.Gotova() {
+		return ErrLicitacijaGotova
+	}
+	if igrac != l.NaPotezu {
+		return ErrNijeNaRedu
+	}
+	if l.Pasirao[igrac] {
+		return ErrVecPasirao
+	}
+	dozvoljena := false
+	for _, v := range l.Dozvoljene(igrac) {
+		if v == ponuda {
+			dozvoljena = true
+		}
+	}
+	if !dozvoljena {
+		if ponuda > Pas && ponuda <= l.Najvisa {
+			return ErrPreniskaPonuda
+		}
+		return ErrNeispravnaPonuda
+	}
+
+	switch {
+	case ponuda == Pas:
+		l.Pasirao[igrac] = true
+		l.BrojPasova++
+	case ponuda >= Igra:
+		l.Najava[igrac] = NazivPonude(ponuda)
+		l.Najvisa = ponuda
+		l.Deklarant = igrac
+	default:
+		l.Licitirao[igrac] = true
+		l.Najvisa = ponuda
+		l.Deklarant = igrac
+	}
+	if l.Gotova() {
+		return nil
+	}
+	next := (igrac + 1) % 3
+	for l.Pasirao[next] {
+		next = (next + 1) % 3
+	}
+	l.NaPotezu = next
+	return nil
+}
@@ -0,0 +1,351 @@
+package preferans
+
+import "math/bits"
+
+// ==== Pretraga sa otvorenim kartama ====
+// Pretraga igra ostatak ruke sa svim kartama na stolu: deklarant igra za svoj
+// cilj, a dvojica protiv njega zajedno igraju suprotno. Tako se proverava da li
+// zahtev (claim) važi protiv bilo koje odbrane. Stanja na početku štiha se
+// pamte. Zahtev se proverava pitanjima da li deklarant ostvaruje zadati broj
+// štihova, koja se seku čim se nađe odlučujući potez i zato nemaju granicu.
+// Izbor karte (NajboljaKarta, saveti) traži tačan broj štihova i pri tome
+// odustaje kad pređe granicu čvorova.
+
+// GranicaPretrage je najveći broj čvorova koje jedan izbor karte sme da obiđe
+const GranicaPretrage = 4_000_000
+
+var (
+	indeksKarte = map[string]int{}
+	bojaKarte   [32]rune
+	rangKarte   [32]int
+)
+
+func init() {
+	for i, c := range Deck {
+		indeksKarte[c] = i
+		r, s := ParseCard(c)
+		bojaKarte[i] = s
+		rangKarte[i] = RankOrder[r]
+	}
+}
+
+type stanjeStiha struct {
+	ruke [3]uint32
+	vodi int
+}
+
+type pretraga struct {
+	adut     rune
+	igrac    int
+	najvise  bool // igrac uzima što više štihova; u betlu što manje
+	memo     map[stanjeStiha]int
+	granica  int
+	cvorova  int
+	prekinut bool
+
+	memoCilja map[stanjeCilja]bool
+}
+
+type stanjeCilja struct {
+	stanjeStiha
+	cilj int
+}
+
+func maska(karte []string) uint32 {
+	var m uint32
+	for _, c := range karte {
+		m |= 1 << indeksKarte[c]
+	}
+	return m
+}
+
+func maskaBoje(ruka uint32, boja rune) uint32 {
+	var m uint32
+	for ruka != 0 {
+		i := bits.TrailingZeros32(ruka)
+		ruka &^= 1 << i
+		if bojaKarte[i] == boja {
+			m |= 1 << i
+		}
+	}
+	return m
+}
+
+// legalne je isto pravilo kao LegalneKarte, nad maskama
+func (p *pretraga) legalne(ruka uint32, stih []int) uint32 {
+	if len(stih) == 0 {
+		return ruka
+	}
+	if m := maskaBoje(ruka, bojaKarte[stih[0]]); m != 0 {
+		return m
+	}
+	if p.adut != 0 {
+		if m := maskaBoje(ruka, p.adut); m != 0 {
+			return m
+		}
+	}
+	return ruka
+}
+
+// izbor su legalne karte bez istovrednih: od karata iste boje u istoj ruci
+// između kojih nema karte koja je još u igri dovoljno je probati najjaču
+func (p *pretraga) izbor(ruke [3]uint32, naPotezu int, stih []int) uint32 {
+	legalne := p.legalne(ruke[naPotezu], stih)
+	uIgri := ruke[0] | ruke[1] | ruke[2]
+	for _, c := range stih {
+		uIgri |= 1 << c
+	}
+	m := legalne
+	for ostale := legalne; ostale != 0; {
+		i := bits.TrailingZeros32(ostale)
+		ostale &^= 1 << i
+		// Deck ide po bojama od po 8 karata, od najslabije do najjače
+		iznad := uIgri &^ (1<<(i+1) - 1) & (0xff << (i / 8 * 8))
+		if iznad != 0 && legalne&(1<<bits.TrailingZeros32(iznad)) != 0 {
+			m &^= 1 << i
+		}
+	}
+	return m
+}
+
+func (p *pretraga) pobednik(stih []int) int {
+	naj := 0
+	for i := 1; i < len(stih); i++ {
+		a, b := stih[naj], stih[i]
+		if bojaKarte[b] == bojaKarte[a] && rangKarte[b] > rangKarte[a] || bojaKarte[b] == p.adut && bojaKarte[a] != p.adut {
+			naj = i
+		}
+	}
+	return naj
+}
+
+// odPocetka vraća koliko štihova igrac nosi od štiha koji počinje vodi
+func (p *pretraga) odPocetka(ruke [3]uint32, vodi int) int {
+	if ruke[0]|ruke[1]|ruke[2] == 0 {
+		return 0
+	}
+	k := stanjeStiha{ruke, vodi}
+	if v, ok := p.memo[k]; ok {
+		return v
+	}
+	v := p.potez(ruke, vodi, vodi, nil)
+	if !p.prekinut {
+		p.memo[k] = v
+	}
+	return v
+}
+
+// potez vraća vrednost stanja u kome je naPotezu na redu u nedovršenom štihu
+func (p *pretraga) potez(ruke [3]uint32, vodi, naPotezu int, stih []int) int {
+	if len(stih) == 3 {
+		uzeo := (vodi + p.pobednik(stih)) % 3
+		v := p.odPocetka(ruke, uzeo)
+		if uzeo == p.igrac {
+			v++
+		}
+		return v
+	}
+	p.cvorova++
+	if p.cvorova > p.granica {
+		p.prekinut = true
+		return 0
+	}
+	najbolje := -1
+	for m := p.izbor(ruke, naPotezu, stih); m != 0 && !p.prekinut; {
+		i := bits.TrailingZeros32(m)
+		m &^= 1 << i
+		v := p.posle(ruke, vodi, naPotezu, stih, i)
+		if najbolje < 0 || p.bolje(naPotezu, v, najbolje) {
+			najbolje = v
+		}
+	}
+	return najbolje
+}
+
+func (p *pretraga) posle(ruke [3]uint32, vodi, naPotezu int, stih []int, karta int) int {
+	ruke[naPotezu] &^= 1 << karta
+	return p.potez(ruke, vodi, (naPotezu+1)%3, append(stih[:len(stih):len(stih)], karta))
+}
+
+// bolje javlja da li je v bolje od dosadašnjeg za igrača na potezu
+func (p *pretraga) bolje(naPotezu, v, dosad int) bool {
+	if (naPotezu == p.igrac) == p.najvise {
+		return v > dosad
+	}
+	return v < dosad
+}
+
+// ispunjava javlja da li igrac iz stanja u kome je naPotezu na redu sigurno
+// ostvaruje cilj: uzima bar cilj štihova, a u betlu najviše cilj. Igrac traži
+// jedan potez koji ispunjava cilj, a protivnici jedan koji ga ruši, pa se
+// pretraga seče čim ga nađe i ne treba joj granica čvorova.
+func (p *pretraga) ispunjava(ruke [3]uint32, vodi, naPotezu int, stih []int, cilj int) bool {
+	if len(stih) == 3 {
+		uzeo := (vodi + p.pobednik(stih)) % 3
+		if uzeo == p.igrac {
+			cilj--
+		}
+		return p.ispunjavaOdPocetka(ruke, uzeo, cilj)
+	}
+	p.cvorova++
+	trazi := naPotezu == p.igrac
+	for m := p.izbor(ruke, naPotezu, stih); m != 0; {
+		i := bits.TrailingZeros32(m)
+		m &^= 1 << i
+		ruke2 := ruke
+		ruke2[naPotezu] &^= 1 << i
+		if p.ispunjava(ruke2, vodi, (naPotezu+1)%3, append(stih[:len(stih):len(stih)], i), cilj) == trazi {
+			return trazi
+		}
+	}
+	return !trazi
+}
+
+func (p *pretraga) ispunjavaOdPocetka(ruke [3]uint32, vodi, cilj int) bool {
+	ostalo := bits.OnesCount32(ruke[0]|ruke[1]|ruke[2]) / 3
+	switch {
+	case p.najvise && cilj <= 0, !p.najvise && cilj >= ostalo:
+		return true
+	case p.najvise && cilj > ostalo, !p.najvise && cilj < 0:
+		return false
+	}
+	k := stanjeCilja{stanjeStiha{ruke, vodi}, cilj}
+	if v, ok := p.memoCilja[k]; ok {
+		return v
+	}
+	v := p.ispunjava(ruke, vodi, vodi, nil, cilj)
+	p.memoCilja[k] = v
+	return v
+}
+
+func (o *Odigravanje) pretraga(igrac int, najvise bool) (*pretraga, [3]uint32, []int) {
+	p := &pretraga{adut: o.Adut, igrac: igrac, najvise: najvise, memo: map[stanjeStiha]int{}, granica: GranicaPretrage, memoCilja: map[stanjeCilja]bool{}}
+	var ruke [3]uint32
+	for i := range ruke {
+		ruke[i] = maska(o.Ruke[i])
+	}
+	stih := make([]int, len(o.Stih))
+	for i, c := range o.Stih {
+		stih[i] = indeksKarte[c]
+	}
+	return p, ruke, stih
+}
+
+// SigurniStihovi vraća koliko još štihova igrac nosi ako igra najbolje, a
+// protivnici zajedno najbolje protiv njega. Za najvise=false igrac pokušava da
+// uzme što manje (betl), a protivnici da mu daju što više. Nedovršen štih se
+// računa. Broj se nalazi polovljenjem preko pitanja da li igrac ostvaruje cilj,
+// sa zajedničkom memorijom, pa je pretraga potpuna i na početku ruke.
+func (o *Odigravanje) SigurniStihovi(igrac int, najvise bool) int {
+	p, ruke, stih := o.pretraga(igrac, najvise)
+	return p.sigurni(ruke, o.Vodi, o.NaPotezu, stih, 10-o.Odigrano)
+}
+
+// sigurni polovljenjem nalazi broj štihova koji igrac ostvaruje od ostalo
+func (p *pretraga) sigurni(ruke [3]uint32, vodi, naPotezu int, stih []int, ostalo int) int {
+	ispunjava := func(cilj int) bool {
+		if len(stih) == 0 {
+			return p.ispunjavaOdPocetka(ruke, vodi, cilj)
+		}
+		return p.ispunjava(ruke, vodi, naPotezu, stih, cilj)
+	}
+	// najvise: najveći cilj koji ispunjava; inače najmanji
+	od, do := 0, ostalo
+	for od < do {
+		if p.najvise {
+			if c := (od + do + 1) / 2; ispunjava(c) {
+				od = c
+			} else {
+				do = c - 1
+			}
+		} else {
+			if c := (od + do) / 2; ispunjava(c) {
+				do = c
+			} else {
+				od = c + 1
+			}
+		}
+	}
+	return od
+}
+
+// Raspodela odigra ostatak ruke najboljom igrom sa otvorenim kartama i vraća
+// koliko još štihova uzima svako mesto. Igrac uzima tačno SigurniStihovi, a
+// protivnici svaki put bacaju prvu kartu koja ga drži na tom broju, pa i
+// njihovi štihovi zavise od karata, a ne od redosleda mesta. Pretraga deli
+// memoriju sa SigurniStihovi, pa je odigravanje jeftino.
+func (o *Odigravanje) Raspodela(igrac int, najvise bool) [3]int {
+	var out [3]int
+	if o.Gotovo() {
+		return out
+	}
+	p, ruke, stih := o.pretraga(igrac, najvise)
+	// igrac drži cilj, a protivnici ne puštaju ni štih preko (u betlu ispod) njega
+	drzi := p.sigurni(ruke, o.Vodi, o.NaPotezu, stih, 10-o.Odigrano)
+	rusi := drzi + 1
+	if !najvise {
+		rusi = drzi - 1
+	}
+	vodi, naPotezu := o.Vodi, o.NaPotezu
+	for ruke[0]|ruke[1]|ruke[2] != 0 {
+		izbor := p.izbor(ruke, naPotezu, stih)
+		karta := bits.TrailingZeros32(izbor)
+		for m := izbor; m != 0; {
+			i := bits.TrailingZeros32(m)
+			m &^= 1 << i
+			ruke2 := ruke
+			ruke2[naPotezu] &^= 1 << i
+			stih2 := append(stih[:len(stih):len(stih)], i)
+			sledeci := (naPotezu + 1) % 3
+			if naPotezu == igrac && p.ispunjava(ruke2, vodi, sledeci, stih2, drzi) ||
+				naPotezu != igrac && !p.ispunjava(ruke2, vodi, sledeci, stih2, rusi) {
+				karta = i
+				break
+			}
+		}
+		ruke[naPotezu] &^= 1 << karta
+		stih = append(stih, karta)
+		if len(stih) < 3 {
+			naPotezu = (naPotezu + 1) % 3
+			continue
+		}
+		uzeo := (vodi + p.pobednik(stih)) % 3
+		out[uzeo]++
+		if uzeo == igrac {
+			drzi--
+			rusi--
+		}
+		vodi, naPotezu, stih = uzeo, uzeo, nil
+	}
+	return out
+}
+
+// NajboljaKarta vraća kartu koju igrač na potezu baca u istoj igri sa otvorenim
+// kartama: igrac igra za svoj cilj, ostali protiv njega
+func (o *Odigravanje) NajboljaKarta(igrac int, najvise bool) (string, bool) {
+	return o.najboljaKarta(igrac, najvise, GranicaPretrage)
+}
+
+func (o *Odigravanje) najboljaKarta(igrac int, najvise bool, granica int) (string, bool) {
+	if o.Gotovo() {
+		return "", false
+	}
+	p, ruke, stih := o.pretraga(igrac, najvise)
+	p.granica = granica
+	karta, najbolje := -1, -1
+	for m := p.izbor(ruke, o.NaPotezu, stih); m != 0; {
+		i := bits.TrailingZeros32(m)
+		m &^= 1 << i
+		v := p.posle(ruke, o.Vodi, o.NaPotezu, stih, i)
+		if p.prekinut {
+			return "", false
+		}
+		if karta < 0 || p.bolje(o.NaPotezu, v, najbolje) {
+			karta, najbolje = i, v
+		}
+	}
+	if karta < 0 {
+		return "", false
+	}
+	return Deck[karta], true
+}
@@ -0,0 +1,49 @@
+package preferans
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// podelaZaPretragu je ista nasumična podela za svako pokretanje
+func podelaZaPretragu(seme int64) [3][]string {
+	ruke, _ := Podeli(ShuffleCards(Deck, rand.New(rand.NewSource(seme))))
+	return ruke
+}
+
+func TestRaspodelaDrziSigurneStihove(t *testing.T) {
+	for seme := range int64(20) {
+		o := NovoOdigravanje(podelaZaPretragu(seme), '♠', int(seme%3))
+		// posle tri štiha, da bi test bio brz
+		rnd := rand.New(rand.NewSource(seme))
+		for range 9 {
+			l := o.Legalne()
+			o.Baci(o.NaPotezu, l[rnd.Intn(len(l))])
+		}
+		for _, najvise := range []bool{true, false} {
+			r := o.Raspodela(0, najvise)
+			if r[0]+r[1]+r[2] != 7 {
+				t.Fatalf("seme %d: raspodela %v nema 7 štihova", seme, r)
+			}
+			if s := o.SigurniStihovi(0, najvise); r[0] != s {
+				t.Fatalf("seme %d, najvise %v: raspodela %v, sigurni %d", seme, najvise, r, s)
+			}
+		}
+	}
+}
+
+// BenchmarkSigurniStihovi meri proveru zahteva na početku ruke, sa svih 10 štihova
+func BenchmarkSigurniStihovi(b *testing.B) {
+	for i := range b.N {
+		o := NovoOdigravanje(podelaZaPretragu(int64(i%8)), '♠', 0)
+		o.SigurniStihovi(0, true)
+	}
+}
+
+// BenchmarkRaspodela meri podelu preostalih štihova posle zahteva na početku ruke
+func BenchmarkRaspodela(b *testing.B) {
+	for i := range b.N {
+		o := NovoOdigravanje(podelaZaPretragu(int64(i%8)), '♠', 0)
+		o.Raspodela(0, true)
+	}
+}
@@ -0,0 +1,135 @@
+package preferans
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rules su kućna pravila sobe. Biraju se pri pravljenju sobe i važe do kraja meča.
+type Rules struct {
+	Naziv         string `json:"naziv"`
+	PocetnaBula   int    `json:"pocetna_bula"`   // bula svakog igrača na početku meča
+	MaxRefe       int    `json:"max_refe"`       // najviše upisanih refea po igraču (0 = bez refea); refe duplira sledeću igru igrača
+	TalonOtkriven bool   `json:"talon_otkriven"` // da li protivnici vide talon koji deklarant uzima
+	MaxKontra     int    `json:"max_kontra"`     // 1: samo kontra, 2: do rekontre, 3: do subkontre
+	Moje          bool   `json:"moje"`           // igrač sa prednošću sme da kaže "moje" na istu ponudu
+	RefeNaDvojci  bool   `json:"refe_na_dvojci"` // igra od 2 bez kontre ne važi: upisuje se refe i deli se ponovo
+	VrednostBetla int    `json:"vrednost_betla"`
+	VrednostSansa int    `json:"vrednost_sansa"`
+	SlobodanChat  bool   `json:"slobodan_chat"` // dozvoljen slobodan chat; brze poruke rade uvek
+
+	// Bodovanje pratilaca
+	SupaPoStihu     int  `json:"supa_po_stihu"`     // koliko vrednosti ponude pratilac dobija po štihu
+	PratilacMora    int  `json:"pratilac_mora"`     // štihova koje svaki pratilac mora da uzme
+	SamPratiZaOba   bool `json:"sam_prati_za_oba"`  // kad prati samo jedan, dobija i štihove drugog branioca
+	PratilacPadaSam bool `json:"pratilac_pada_sam"` // pratilac pada zbog svojih štihova, bez obzira na drugog
+}
+
+// Presets su imenovana pravila koja se mogu izabrati pri ulasku u sobu
+var Presets = map[string]Rules{
+	"standard": {
+		Naziv:         "standard",
+		PocetnaBula:   100,
+		MaxRefe:       3,
+		TalonOtkriven: true,
+		MaxKontra:     3,
+		Moje:          true,
+		RefeNaDvojci:  true,
+		VrednostBetla: Betl,
+		VrednostSansa: Sans,
+		SlobodanChat:  true,
+		SupaPoStihu:   1,
+		PratilacMora:  2,
+		SamPratiZaOba: true,
+	},
+	"klub": {
+		Naziv:           "klub",
+		PocetnaBula:     60,
+		MaxRefe:         2,
+		TalonOtkriven:   false,
+		MaxKontra:       2,
+		Moje:            false,
+		RefeNaDvojci:    false,
+		VrednostBetla:   12,
+		VrednostSansa:   14,
+		SlobodanChat:    false,
+		SupaPoStihu:     1,
+		PratilacMora:    2,
+		SamPratiZaOba:   false,
+		PratilacPadaSam: true,
+	},
+}
+
+// DefaultRules je naziv pravila koja se koriste kad klijent ne izabere nijedna
+const DefaultRules = "standard"
+
+// RulesZa vraća pravila po nazivu; prazan naziv daje podrazumevana
+func RulesZa(naziv string) (Rules, error) {
+	if naziv == "" {
+		naziv = DefaultRules
+	}
+	r, ok := Presets[naziv]
+	if !ok {
+		return Rules{}, fmt.Errorf("nepoznata pravila %q (dostupna: %s)", naziv, strings.Join(NaziviPravila(), ", "))
+	}
+	return r, nil
+}
+
+// NaziviPravila vraća sortirane nazive svih pravila
+func NaziviPravila() []string {
+	out := make([]string, 0, len(Presets))
+	for ime := range Presets {
+		out = append(out, ime)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Validate proverava da li su pravila smislena
+func (r Rules) Validate() error {
+	switch {
+	case r.PocetnaBula <= 0:
+		return fmt.Errorf("pravila %q: početna bula mora biti pozitivna", r.Naziv)
+	case r.MaxRefe < 0:
+		return fmt.Errorf("pravila %q: max refe ne može biti negativan", r.Naziv)
+	case r.MaxKontra < 0 || r.MaxKontra > MaxKontra:
+		return fmt.Errorf("pravila %q: nivo kontre mora biti od 0 do %d", r.Naziv, MaxKontra)
+	case r.VrednostBetla <= 0 || r.VrednostSansa <= 0:
+		return fmt.Errorf("pravila %q: vrednosti betla i sansa moraju biti pozitivne", r.Naziv)
+	case r.PratilacMora < 0 || r.PratilacMora > 5:
+		return fmt.Errorf("pravila %q: pratilac mora uzeti od 0 do 5 štihova", r.Naziv)
+	}
+	return nil
+}
+
+// Vrednost igre u bulama; svaka kontra je udvostručava, ali ne preko plafona iz pravila
+func (r Rules) Vrednost(ponuda, kontraStatus int) int {
+	osnova := ponuda
+	switch ponuda {
+	case Betl:
+		osnova = r.VrednostBetla
+	case Sans:
+		osnova = r.VrednostSansa
+	}
+	return 2 * osnova * KontraMultiplier(min(kontraStatus, r.MaxKontra))
+}
+
+// SmeKontru govori da li posle datog nivoa sme da se kaže sledeća kontra
+func (r Rules) SmeKontru(kontraStatus int) bool {
+	return kontraStatus < r.MaxKontra
+}
+
+// SmeMoje: igrač koji je ranije po redu licitiranja (bliži prvom) može da zadrži
+// istu ponudu koju je dao igrač posle njega
+func (r Rules) SmeMoje(igrac, nosilac, prvi int) bool {
+	if !r.Moje || nosilac < 0 || igrac == nosilac {
+		return false
+	}
+	return (igrac-prvi+3)%3 < (nosilac-prvi+3)%3
+}
+
+// NevazecaIgra je "igra od 2 bez kontre ne važi"
+func (r Rules) NevazecaIgra(ponuda, kontraStatus int) bool {
+	return r.RefeNaDvojci && ponuda == MinBroj && kontraStatus == 0
+}
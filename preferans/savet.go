@@ -0,0 +1,270 @@
+package preferans
+
+import (
+	"math/rand"
+	"slices"
+)
+
+// ==== Saveti za učenje ====
+// Procena ruke je namerno jednostavna: broji visoke karte koje mogu da se
+// zaštite, dugačke boje i sečenje adutom. Za bacanje karte savet odigra ostatak
+// ruke na više nasumičnih rasporeda karata koje igrač ne vidi (pretraga sa
+// otvorenim kartama) i predlaže kartu koja je najčešće najbolja.
+
+const (
+	talonDonosi    = 0.7 // koliko štihova talon u proseku doda deklarantu
+	brojUzoraka    = 16
+	granicaUzorka  = 25_000
+	pragKontre     = 3.0 // toliko štihova braniocu treba za kontru
+	pragBezTalona  = 6.5 // igra i sans bez talona traže malo sigurnosti
+	potrebnoZaIgru = 6
+)
+
+// Savet je predlog sa kratkim objašnjenjem za igrača. Objašnjenje je kod
+// poruke sa parametrima, a tekst na jeziku igrača pravi server.
+type Savet struct {
+	Predlog string   // ponuda, adut, "prati"/"kontra"/"dalje" ili karta
+	Karte   []string // karte za škart
+	Kod     string
+	Param   []any
+}
+
+// ProcenaStihova procenjuje koliko štihova ruka nosi sa datim adutom (0 je bez aduta)
+func ProcenaStihova(ruka []string, adut rune) float64 {
+	ukupno := 0.0
+	duzine := map[rune]int{}
+	for _, b := range Suits {
+		rangovi := []int{}
+		for _, c := range kartBoje(ruka, b) {
+			r, _ := ParseCard(c)
+			rangovi = append(rangovi, RankOrder[r])
+		}
+		slices.Sort(rangovi)
+		slices.Reverse(rangovi)
+		n := len(rangovi)
+		duzine[b] = n
+		// karta nosi štih ako ispod nje ima dovoljno nižih karata da je sačuva
+		// dok ne izađu jače karte koje su kod protivnika
+		for i, r := range rangovi {
+			nedostaje := len(RankOrder) - r - i
+			nizih := n - i - 1
+			switch {
+			case nedostaje == 0:
+				ukupno++
+			case nedostaje == 1 && nizih >= 1:
+				ukupno += 0.5
+			case nedostaje == 2 && nizih >= 2:
+				ukupno += 0.25
+			}
+		}
+		// dugačka boja nosi i male karte kad protivnici ostanu bez nje
+		if b == adut {
+			ukupno += float64(max(0, n-3))
+		} else {
+			ukupno += 0.5 * float64(max(0, n-4))
+		}
+	}
+	if adut != 0 {
+		secenje := 0.0
+		for _, b := range Suits {
+			switch {
+			case b == adut:
+			case duzine[b] == 0:
+				secenje++
+			case duzine[b] == 1:
+				secenje += 0.5
+			}
+		}
+		ukupno += min(secenje, 0.5*float64(max(0, duzine[adut]-2)))
+	}
+	return ukupno
+}
+
+// NajboljiAdut vraća boju sa kojom ruka nosi najviše štihova
+func NajboljiAdut(ruka []string) (rune, float64) {
+	adut, naj := Suits[0], -1.0
+	for _, b := range Suits {
+		if v := ProcenaStihova(ruka, b); v > naj {
+			adut, naj = b, v
+		}
+	}
+	return adut, naj
+}
+
+// BetlSiguran javlja da li u svakoj boji ima dovoljno niskih karata da se
+// nijedan štih ne mora uzeti: k-ta najniža karta boje (od nule) nije jača od 2k+1
+func BetlSiguran(ruka []string) bool {
+	for _, b := range Suits {
+		rangovi := []int{}
+		for _, c := range kartBoje(ruka, b) {
+			r, _ := ParseCard(c)
+			rangovi = append(rangovi, RankOrder[r])
+		}
+		slices.Sort(rangovi)
+		for k, r := range rangovi {
+			if r > 2*k+1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// SavetLicitacija predlaže jednu od dozvoljenih ponuda
+func SavetLicitacija(ruka []string, dozvoljene []int) Savet {
+	adut, sa := NajboljiAdut(ruka)
+	bez := ProcenaStihova(ruka, 0)
+	sme := func(v int) bool { return slices.Contains(dozvoljene, v) }
+	switch {
+	case sme(Betl) && BetlSiguran(ruka):
+		return Savet{Predlog: "betl", Kod: "savet_betl"}
+	case sme(Sans) && bez >= pragBezTalona:
+		return Savet{Predlog: "sans", Kod: "savet_sans", Param: []any{bez}}
+	case sme(Igra) && sa >= pragBezTalona:
+		return Savet{Predlog: "igra", Kod: "savet_igra", Param: []any{string(adut), sa}}
+	case sa+talonDonosi >= potrebnoZaIgru:
+		for _, v := range dozvoljene {
+			if v >= MinBroj && v <= MaxBroj {
+				return Savet{Predlog: NazivPonude(v), Kod: "savet_licitiraj", Param: []any{string(adut), sa, talonDonosi}}
+			}
+		}
+	}
+	return Savet{Predlog: "pas", Kod: "savet_pas", Param: []any{sa, string(adut), potrebnoZaIgru}}
+}
+
+// SavetAdut predlaže adut za ruku (sa talonom, ako je već u ruci)
+func SavetAdut(ruka []string) Savet {
+	adut, sa := NajboljiAdut(ruka)
+	return Savet{Predlog: string(adut), Kod: "savet_adut", Param: []any{string(adut), sa}}
+}
+
+// SavetSkart bira dve karte od dvanaest čijim odbacivanjem ruka najmanje gubi
+func SavetSkart(ruka []string, adut rune) Savet {
+	if len(ruka) != 12 {
+		return Savet{Kod: "savet_skart_bez_talona"}
+	}
+	var skart []string
+	naj, najRang := -1.0, 0
+	for i := range ruka {
+		for j := i + 1; j < len(ruka); j++ {
+			ostatak, _ := Ukloni(ruka, ruka[i], ruka[j])
+			v := ProcenaStihova(ostatak, adut)
+			r1, _ := ParseCard(ruka[i])
+			r2, _ := ParseCard(ruka[j])
+			// kod iste procene odbacuju se niže karte
+			rang := RankOrder[r1] + RankOrder[r2]
+			if v > naj || v == naj && rang < najRang {
+				skart, naj, najRang = []string{ruka[i], ruka[j]}, v, rang
+			}
+		}
+	}
+	return Savet{Karte: skart, Kod: "savet_skart", Param: []any{skart[0], skart[1], naj}}
+}
+
+// SavetKontra predlaže braniocu da li da da kontru na igru deklaranta
+func SavetKontra(ruka []string, adut rune, ponuda int) Savet {
+	if ponuda == Betl {
+		return Savet{Predlog: "dalje", Kod: "savet_kontra_betl"}
+	}
+	moji := ProcenaStihova(ruka, adut)
+	if moji >= pragKontre {
+		return Savet{Predlog: "kontra", Kod: "savet_kontra", Param: []any{moji, potrebnoZaIgru}}
+	}
+	return Savet{Predlog: "dalje", Kod: "savet_dalje", Param: []any{moji}}
+}
+
+// SavetRekontra predlaže deklarantu rekontru samo kad je igra sigurna: betl
+// bez štiha koji se mora uzeti, ili štih više od potrebnog
+func SavetRekontra(ruka []string, adut rune, ponuda int) Savet {
+	if ponuda == Betl {
+		if BetlSiguran(ruka) {
+			return Savet{Predlog: "kontra", Kod: "savet_rekontra_betl"}
+		}
+		return Savet{Predlog: "dalje", Kod: "savet_bez_rekontre_betl"}
+	}
+	moji := ProcenaStihova(ruka, adut)
+	if moji >= potrebnoZaIgru+1 {
+		return Savet{Predlog: "kontra", Kod: "savet_rekontra", Param: []any{moji, potrebnoZaIgru}}
+	}
+	return Savet{Predlog: "dalje", Kod: "savet_bez_rekontre", Param: []any{moji, potrebnoZaIgru}}
+}
+
+// SavetPracenje predlaže braniocu da li da prati igru: prati kad ruka nosi
+// bar onoliko štihova koliko pratilac mora da uzme. Betl se prati uvek, jer se
+// pratiocu u betlu ne upisuje ni bula ni supa.
+func SavetPracenje(ruka []string, adut rune, ponuda, mora int) Savet {
+	if ponuda == Betl {
+		return Savet{Predlog: "prati", Kod: "savet_prati_betl"}
+	}
+	moji := ProcenaStihova(ruka, adut)
+	if moji >= float64(mora) {
+		return Savet{Predlog: "prati", Kod: "savet_prati", Param: []any{moji, mora}}
+	}
+	return Savet{Predlog: "dalje", Kod: "savet_ne_prati", Param: []any{moji, mora}}
+}
+
+// SavetKarta predlaže kartu igraču ja koji je na potezu. Od tuđih ruku koristi
+// se samo broj karata: nepoznate su karte koje igrač ne vidi, a otvorene ruke
+// koje su svima pokazane (zahtev). rnd može biti nil.
+func SavetKarta(o *Odigravanje, ja, deklarant int, najvise bool, nepoznate []string, otvorene map[int][]string, rnd *rand.Rand) Savet {
+	legalne := o.Legalne()
+	if len(legalne) == 1 {
+		return Savet{Predlog: legalne[0], Kod: "savet_jedina_karta"}
+	}
+	glasovi := map[string]int{}
+	uzoraka := 0
+	for range brojUzoraka {
+		k := *o
+		k.Stih = slices.Clone(o.Stih)
+		spil := ShuffleCards(nepoznate, rnd)
+		ok := true
+		for j := range k.Ruke {
+			switch {
+			case j == ja:
+				k.Ruke[j] = slices.Clone(o.Ruke[ja])
+			case otvorene[j] != nil:
+				k.Ruke[j] = slices.Clone(otvorene[j])
+			case len(o.Ruke[j]) <= len(spil):
+				k.Ruke[j], spil = spil[:len(o.Ruke[j])], spil[len(o.Ruke[j]):]
+			default:
+				ok = false
+			}
+		}
+		if !ok {
+			break
+		}
+		if c, ok := k.najboljaKarta(deklarant, najvise, granicaUzorka); ok {
+			glasovi[c]++
+			uzoraka++
+		}
+	}
+	if uzoraka == 0 {
+		return jednostavnaKarta(o, legalne)
+	}
+	naj := ""
+	for _, c := range legalne {
+		if naj == "" || glasovi[c] > glasovi[naj] {
+			naj = c
+		}
+	}
+	return Savet{Predlog: naj, Kod: "savet_karta", Param: []any{naj, glasovi[naj], uzoraka}}
+}
+
+// jednostavnaKarta: najniža karta koja trenutno nosi štih, inače najniža karta
+func jednostavnaKarta(o *Odigravanje, legalne []string) Savet {
+	karte := slices.Clone(legalne)
+	SortCards(karte)
+	slices.SortStableFunc(karte, func(a, b string) int {
+		ra, _ := ParseCard(a)
+		rb, _ := ParseCard(b)
+		return RankOrder[ra] - RankOrder[rb]
+	})
+	if len(o.Stih) > 0 {
+		for _, c := range karte {
+			if PobednikStiha(append(slices.Clone(o.Stih), c), o.Adut) == len(o.Stih) {
+				return Savet{Predlog: c, Kod: "savet_nosi", Param: []any{c}}
+			}
+		}
+	}
+	return Savet{Predlog: karte[0], Kod: "savet_najniza", Param: []any{karte[0]}}
+}
@@ -0,0 +1,142 @@
+package preferans
+
+import (
+	"errors"
+	"slices"
+)
+
+var (
+	ErrIgraGotova   = errors.New("igra je završena")
+	ErrNijeNaPotezu = errors.New("nisi na potezu")
+	ErrNemasKartu   = errors.New("nemaš tu kartu")
+	ErrMorasBoju    = errors.New("moraš odgovoriti na boju ili seći adutom")
+)
+
+// AdutIzStila pretvara izbor adut iz poruke ("♠", "pik", ...) u boju; 0 znači bez aduta
+func AdutIzStila(stil string) rune {
+	switch stil {
+	case "♠", "pik":
+		return '♠'
+	case "♦", "karo":
+		return '♦'
+	case "♥", "herc":
+		return '♥'
+	case "♣", "tref":
+		return '♣'
+	}
+	return 0
+}
+
+// LegalneKarte: mora se odgovoriti na boju; ako nema boje mora se seći adutom
+func LegalneKarte(ruka, stih []string, adut rune) []string {
+	if len(stih) == 0 {
+		return append([]string{}, ruka...)
+	}
+	prva := Suit(stih[0])
+	if u := kartBoje(ruka, prva); len(u) > 0 {
+		return u
+	}
+	if adut != 0 {
+		if u := kartBoje(ruka, adut); len(u) > 0 {
+			return u
+		}
+	}
+	return append([]string{}, ruka...)
+}
+
+func kartBoje(ruka []string, boja rune) []string {
+	out := []string{}
+	for _, c := range ruka {
+		if Suit(c) == boja {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Legalna proverava da li igrač sme da baci kartu na trenutni štih
+func Legalna(ruka, stih []string, adut rune, card string) bool {
+	return Sadrzi(LegalneKarte(ruka, stih, adut), card)
+}
+
+// PobednikStiha vraća indeks karte u štihu (0 je karta koja je prva bačena) koja nosi štih
+func PobednikStiha(stih []string, adut rune) int {
+	naj := 0
+	for i := 1; i < len(stih); i++ {
+		r1, s1 := ParseCard(stih[naj])
+		r2, s2 := ParseCard(stih[i])
+		if s2 == s1 && RankOrder[r2] > RankOrder[r1] || s2 == adut && s1 != adut {
+			naj = i
+		}
+	}
+	return naj
+}
+
+// Odigravanje prati bacanje karata kroz deset štihova
+type Odigravanje struct {
+	Ruke     [3][]string
+	Adut     rune
+	Vodi     int // ko je bacio prvu kartu u trenutnom štihu
+	NaPotezu int
+	Stih     []string
+	Stihovi  [3]int
+	Odigrano int // broj završenih štihova
+}
+
+// NovoOdigravanje počinje igru; prvi baca igrač prvi
+func NovoOdigravanje(ruke [3][]string, adut rune, prvi int) *Odigravanje {
+	o := &Odigravanje{Adut: adut, Vodi: prvi, NaPotezu: prvi}
+	for i := range ruke {
+		o.Ruke[i] = append([]string{}, ruke[i]...)
+	}
+	return o
+}
+
+// Kopija vraća odigravanje koje ne deli ruke ni štih sa o, na primer za
+// pretragu van brave stola
+func (o *Odigravanje) Kopija() *Odigravanje {
+	k := *o
+	k.Stih = slices.Clone(o.Stih)
+	for i := range k.Ruke {
+		k.Ruke[i] = slices.Clone(o.Ruke[i])
+	}
+	return &k
+}
+
+func (o *Odigravanje) Gotovo() bool {
+	return o.Odigrano == 10
+}
+
+// Legalne vraća karte koje igrač na potezu sme da baci
+func (o *Odigravanje) Legalne() []string {
+	return LegalneKarte(o.Ruke[o.NaPotezu], o.Stih, o.Adut)
+}
+
+// Baci baca kartu igrača na potezu. Kada se štih zatvori vraća ko ga je uzeo, inače -1.
+func (o *Odigravanje) Baci(igrac int, card string) (int, error) {
+	if o.Gotovo() {
+		return -1, ErrIgraGotova
+	}
+	if igrac != o.NaPotezu {
+		return -1, ErrNijeNaPotezu
+	}
+	if !Legalna(o.Ruke[igrac], o.Stih, o.Adut, card) {
+		if !Sadrzi(o.Ruke[igrac], card) {
+			return -1, ErrNemasKartu
+		}
+		return -1, ErrMorasBoju
+	}
+	o.Ruke[igrac], _ = Ukloni(o.Ruke[igrac], card)
+	o.Stih = append(o.Stih, card)
+	if len(o.Stih) < 3 {
+		o.NaPotezu = (igrac + 1) % 3
+		return -1, nil
+	}
+	uzeo := (o.Vodi + PobednikStiha(o.Stih, o.Adut)) % 3
+	o.Stihovi[uzeo]++
+	o.Odigrano++
+	o.Stih = nil
+	o.Vodi = uzeo
+	o.NaPotezu = uzeo
+	return uzeo, nil
+}
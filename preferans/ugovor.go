@@ -0,0 +1,79 @@
+package preferans
+
+import "fmt"
+
+// Ponude u licitaciji. Brojevi 2–7 su obične igre u kojima deklarant bira adut,
+// a igra, betl i sans se najavljuju bez licitiranja i jači su od svake broja.
+const (
+	Pas       = 0
+	Igra      = 8
+	Betl      = 9
+	Sans      = 10
+	MinBroj   = 2
+	MaxBroj   = 7
+	MaxKontra = 3
+)
+
+// Deklaracije preslikavaju najavu iz poruke "igra" u vrednost ponude
+var Deklaracije = map[string]int{"igra": Igra, "betl": Betl, "sans": Sans}
+
+// KontraNazivi su nazivi nivoa kontre (1: kontra, 2: rekontra, 3: subkontra)
+var KontraNazivi = []string{"kontru", "rekontru", "subkontru"}
+
+// NazivPonude vraća tekst ponude kakav šalje klijent ("2".."7", "igra", "betl", "sans")
+func NazivPonude(v int) string {
+	for ime, val := range Deklaracije {
+		if val == v {
+			return ime
+		}
+	}
+	if v == Pas {
+		return "pas"
+	}
+	return fmt.Sprint(v)
+}
+
+// JačaDeklaracija poredi dve najave bez talona
+func JačaDeklaracija(a, b string) bool {
+	redosled := map[string]int{"igra": 1, "betl": 2, "sans": 3}
+	return redosled[a] > redosled[b]
+}
+
+// SaTalonom govori da li deklarant uzima talon i bira štil
+func SaTalonom(ponuda int) bool {
+	return ponuda <= 5
+}
+
+// BiraAdut govori da li deklarant bira adut (u betlu i sansu aduta nema)
+func BiraAdut(ponuda int) bool {
+	return ponuda != Betl && ponuda != Sans
+}
+
+func KontraMultiplier(kontraStatus int) int {
+	switch kontraStatus {
+	case 1:
+		return 2
+	case 2:
+		return 4
+	case 3:
+		return 8
+	default:
+		return 1
+	}
+}
+
+// PotrebnoStihova vraća koliko štihova deklarant mora da uzme (u betlu nijedan)
+func PotrebnoStihova(ponuda int) int {
+	if ponuda == Betl {
+		return 0
+	}
+	return 6
+}
+
+// Prosao proverava da li je deklarant ispunio ugovor
+func Prosao(ponuda, stihova int) bool {
+	if ponuda == Betl {
+		return stihova == 0
+	}
+	return stihova >= PotrebnoStihova(ponuda)
+}
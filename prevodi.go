@@ -0,0 +1,427 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"multiplayer-game/preferans"
+)
+
+// ==== Prevodi ====
+// Svaki tekst koji server šalje kroz websocket je Poruka: stabilan kod i
+// parametri. Tekst se pravi tek pri slanju, na jeziku veze (/ws?jezik=en ili
+// poruka {"type":"jezik"}), pa ista poruka stola stiže svakome na njegovom
+// jeziku. Uz tekst idu i "kod" i "param", za klijente koji prevode sami.
+// Ćirilica se pravi iz latinice pri pokretanju.
+
+const (
+	podrazumevaniJezik = "sr"
+	kodSpoj            = "spoj" // rečenice iz parametara, spojene razmakom
+)
+
+// Jezici su jezici na kojima server piše poruke
+var Jezici = []string{"sr", "sr-Cyrl", "en"}
+
+// Poruka je tekst za klijenta; parametar može biti i Poruka (npr. naziv ugovora)
+type Poruka struct {
+	Kod   string `json:"kod"`
+	Param []any  `json:"param,omitempty"`
+}
+
+func tr(kod string, param ...any) *Poruka {
+	return &Poruka{Kod: kod, Param: param}
+}
+
+// spoj pravi jednu poruku od više rečenica
+func spoj(delovi ...*Poruka) *Poruka {
+	p := &Poruka{Kod: kodSpoj}
+	for _, d := range delovi {
+		p.Param = append(p.Param, d)
+	}
+	return p
+}
+
+// Error: Poruka može da se vrati kao greška, a van websocketa je na srpskom
+func (p *Poruka) Error() string {
+	return p.tekst(podrazumevaniJezik)
+}
+
+func (p *Poruka) tekst(jezik string) string {
+	param := make([]any, len(p.Param))
+	for i, x := range p.Param {
+		if q, ok := x.(*Poruka); ok {
+			x = q.tekst(jezik)
+		}
+		param[i] = x
+	}
+	if p.Kod == kodSpoj {
+		delovi := make([]string, len(param))
+		for i, x := range param {
+			delovi[i] = fmt.Sprint(x)
+		}
+		return strings.Join(delovi, " ")
+	}
+	prevodi, ok := Katalog[p.Kod]
+	if !ok {
+		return p.Kod
+	}
+	format, ok := prevodi[jezik]
+	if !ok {
+		format = prevodi[podrazumevaniJezik]
+	}
+	return fmt.Sprintf(format, param...)
+}
+
+// prevedi vraća poruku sa tekstom na datom jeziku; polje "message" koje nije
+// Poruka (npr. obaveštenje administratora) ide kako jeste
+func prevedi(msg map[string]any, jezik string) map[string]any {
+	p, ok := msg["message"].(*Poruka)
+	if !ok {
+		return msg
+	}
+	out := make(map[string]any, len(msg)+2)
+	for k, v := range msg {
+		out[k] = v
+	}
+	out["message"] = p.tekst(jezik)
+	out["kod"] = p.Kod
+	if len(p.Param) > 0 {
+		out["param"] = p.Param
+	}
+	return out
+}
+
+// jezikIz prepoznaje oznaku jezika (sr, sr-Latn, sr-Cyrl, en-US...); "" ako je nepoznata
+func jezikIz(oznaka string) string {
+	oznaka = strings.ToLower(strings.TrimSpace(oznaka))
+	switch {
+	case oznaka == "sr-cyrl" || strings.HasPrefix(oznaka, "sr-cyrl-"):
+		return "sr-Cyrl"
+	case oznaka == "sr" || strings.HasPrefix(oznaka, "sr-"):
+		return "sr"
+	case oznaka == "en" || strings.HasPrefix(oznaka, "en-"):
+		return "en"
+	}
+	return ""
+}
+
+// jezikZahteva bira jezik veze: ?jezik=, pa Accept-Language, pa konfig.Jezik
+func jezikZahteva(r *http.Request) string {
+	if j := jezikIz(r.URL.Query().Get("jezik")); j != "" {
+		return j
+	}
+	for _, deo := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		oznaka, _, _ := strings.Cut(deo, ";")
+		if j := jezikIz(oznaka); j != "" {
+			return j
+		}
+	}
+	return konfig.Jezik
+}
+
+func (v *veza) postaviJezik(jezik string) {
+	v.jezik.Store(&jezik)
+}
+
+// promeniJezik menja jezik veze igrača ili gledaoca, {"type":"jezik","jezik":"sr-Cyrl"};
+// zove se sa zaključanim r.mu
+func (r *Room) promeniJezik(p *Player, oznaka string) {
+	j := jezikIz(oznaka)
+	if j == "" {
+		r.greska(p, tr("nepoznat_jezik", oznaka, strings.Join(Jezici, ", ")))
+		return
+	}
+	p.conn.postaviJezik(j)
+	r.posalji(p, map[string]any{
+		"type":    "jezik",
+		"jezik":   j,
+		"message": tr("jezik_postavljen"),
+	})
+}
+
+// greskePravila su greške paketa preferans koje stižu do igrača
+var greskePravila = map[error]string{
+	preferans.ErrIgraGotova:       "igra_gotova",
+	preferans.ErrNijeNaPotezu:     "nije_na_potezu",
+	preferans.ErrNemasKartu:       "nemas_kartu",
+	preferans.ErrMorasBoju:        "moras_boju",
+	preferans.ErrNijeNaRedu:       "nije_na_redu",
+	preferans.ErrVecPasirao:       "vec_pasirao",
+	preferans.ErrPreniskaPonuda:   "preniska_ponuda",
+	preferans.ErrNeispravnaPonuda: "neispravna_ponuda",
+	preferans.ErrLicitacijaGotova: "licitacija_gotova",
+}
+
+// porukaGreske pravi poruku od greške; nepoznata greška ide kao tekst
+func porukaGreske(err error) any {
+	var p *Poruka
+	if errors.As(err, &p) {
+		return p
+	}
+	for e, kod := range greskePravila {
+		if errors.Is(err, e) {
+			return tr(kod)
+		}
+	}
+	return err.Error()
+}
+
+// nepoznataPravila je greška za naziv pravila koji ne postoji
+func nepoznataPravila(naziv string) *Poruka {
+	return tr("nepoznata_pravila", naziv, strings.Join(preferans.NaziviPravila(), ", "))
+}
+
+// ugovor je naziv ponude kao parametar poruke
+func ugovor(ponuda int) any {
+	naziv := preferans.NazivPonude(ponuda)
+	if _, ok := Katalog["ugovor_"+naziv]; ok {
+		return tr("ugovor_" + naziv)
+	}
+	return naziv
+}
+
+// Katalog: kod -> jezik -> format za fmt.Sprintf; redosled parametara se u
+// prevodu menja sa %[n]s
+var Katalog = map[string]map[string]string{
+	// licitacija
+	"karte_podeljene":     {"sr": "Karte su podeljene, počinje licitacija.", "en": "Cards are dealt, the auction begins."},
+	"tvoj_red_licitacija": {"sr": "Tvoj je red za licitaciju, izaberi ponudu ili pas.", "en": "Your turn to bid: choose a bid or pass."},
+	"pas":                 {"sr": "%s kaže pas", "en": "%s passes"},
+	"svi_pas":             {"sr": "Svi igrači su rekli pas. Nova podela.", "en": "Everyone passed. New deal."},
+	"licitira":            {"sr": "%s licitira %d", "en": "%s bids %d"},
+	"moje":                {"sr": "%s kaže moje (%d)", "en": "%s holds (%d)"},
+	"deklarise":           {"sr": "%s deklariše: %s", "en": "%s declares: %s"},
+	"preniska_ponuda":     {"sr": "Ponuda mora biti veća od trenutne.", "en": "The bid must be higher than the current one."},
+	"potvrdi_igru":        {"sr": "Potvrdi šta igraš ili najavi veću igru.", "en": "Confirm your contract or announce a higher one."},
+	"ceka_potvrdu":        {"sr": "Čeka se potvrda deklaranta.", "en": "Waiting for the declarer to confirm."},
+	"potvrdjuje_igru":     {"sr": "%s potvrđuje igru: %s", "en": "%s confirms the contract: %s"},
+	"ugovor_igra":         {"sr": "igra", "en": "game"},
+	"ugovor_betl":         {"sr": "betl", "en": "misère"},
+	"ugovor_sans":         {"sr": "sans", "en": "no trumps"},
+	"ugovor_pas":          {"sr": "pas", "en": "pass"},
+	"neponudjena_ponuda":  {"sr": "Izaberi jednu od ponuđenih ponuda.", "en": "Choose one of the offered bids."},
+
+	// talon i kontra
+	"talon_otkriven":       {"sr": "Otkriven je talon.", "en": "The talon is revealed."},
+	"biraj_stil":           {"sr": "Izaberi dve karte za štil.", "en": "Choose two cards to discard."},
+	"bira_adut":            {"sr": "Deklarant %s bira adut: %s", "en": "Declarer %s chooses trumps: %s"},
+	"skart_dve":            {"sr": "Moraš odbaciti tačno 2 karte!", "en": "You must discard exactly 2 cards!"},
+	"neispravan_adut":      {"sr": "Izaberi jedan od ponuđenih aduta.", "en": "Choose one of the offered trump suits."},
+	"adut_se_ne_bira":      {"sr": "U ovoj igri se adut ne bira uz potvrdu.", "en": "Trumps are not chosen with the confirmation in this contract."},
+	"neponudjen_odgovor":   {"sr": "Izaberi jedan od ponuđenih odgovora.", "en": "Choose one of the offered answers."},
+	"igra_sa_refeom":       {"sr": "%s ima otvoren refe, ova igra vredi duplo.", "en": "%s has an open refe, this contract counts double."},
+	"prati_pitanje":        {"sr": "%s igra %s. Da li pratiš?", "en": "%s plays %s. Do you defend?"},
+	"prati":                {"sr": "%s prati.", "en": "%s defends."},
+	"ne_prati":             {"sr": "%s ne prati.", "en": "%s does not defend."},
+	"niko_ne_prati":        {"sr": "Niko ne prati, %s prolazi bez igre.", "en": "Nobody defends, %s makes the contract without play."},
+	"ceka_pracenje":        {"sr": "Čekamo da protivnici kažu da li prate...", "en": "Waiting for the defenders to decide whether to defend..."},
+	"kontra_pitanje_talon": {"sr": "Da li %s može da igra ili kontriraš?", "en": "Can %s make it, or do you double?"},
+	"kontra_pitanje":       {"sr": "Da li daješ kontru?", "en": "Do you double?"},
+	"kontra_dalje_pitanje": {"sr": "%s daje %s. Da li daješ %s?", "en": "%s announces %s. Do you announce %s?"},
+	"ceka_kontru":          {"sr": "Čeka se odluka o kontri...", "en": "Waiting for the decision on doubling..."},
+	"daje_kontru":          {"sr": "%s daje %s.", "en": "%s announces %s."},
+	"kontra_1":             {"sr": "kontru", "en": "double"},
+	"kontra_2":             {"sr": "rekontru", "en": "redouble"},
+	"kontra_3":             {"sr": "subkontru", "en": "subcontra"},
+	"nevazeca_igra":        {"sr": "Igra od 2 bez kontre ne važi. Upisuje se refe i nova podela.", "en": "A 2 without a double does not count. Everyone gets a refe and the cards are redealt."},
+
+	// igra
+	"pocetak_igre":      {"sr": "%s počinje igru sa adutom %s", "en": "%s starts the play, trumps %s"},
+	"na_potezu":         {"sr": "%s je na potezu. Baci kartu.", "en": "%s to play a card."},
+	"baca_kartu":        {"sr": "%s baca %s", "en": "%s plays %s"},
+	"nosi_stih":         {"sr": "%s nosi štih.", "en": "%s wins the trick."},
+	"ceka_zahtev":       {"sr": "Čeka se odgovor na zahtev deklaranta.", "en": "Waiting for the answer to the declarer's claim."},
+	"obracun_prosao":    {"sr": "%s je prošao (štihovi: %v).", "en": "%s made the contract (tricks: %v)."},
+	"obracun_pao":       {"sr": "%s je pao (štihovi: %v).", "en": "%s went down (tricks: %v)."},
+	"igra_gotova":       {"sr": "Igra je završena.", "en": "The play is over."},
+	"nije_na_potezu":    {"sr": "Nisi na potezu.", "en": "It is not your turn."},
+	"nemas_kartu":       {"sr": "Nemaš tu kartu.", "en": "You do not have that card."},
+	"moras_boju":        {"sr": "Moraš odgovoriti na boju ili seći adutom.", "en": "You must follow suit or trump."},
+	"nije_na_redu":      {"sr": "Nije tvoj red za licitaciju.", "en": "It is not your turn to bid."},
+	"vec_pasirao":       {"sr": "Već si rekao pas.", "en": "You have already passed."},
+	"neispravna_ponuda": {"sr": "Neispravna ponuda.", "en": "Invalid bid."},
+	"licitacija_gotova": {"sr": "Licitacija je završena.", "en": "The auction is over."},
+
+	// meč, revanš i turnir
+	"kraj_meca":          {"sr": "Zbir bula je pao na nulu — meč je završen.", "en": "The bula total reached zero — the match is over."},
+	"revans_pitanje":     {"sr": "Igramo revanš?", "en": "Rematch?"},
+	"revans_odbija":      {"sr": "%s ne želi revanš.", "en": "%s declines the rematch."},
+	"revans_prihvata":    {"sr": "%s prihvata revanš.", "en": "%s accepts the rematch."},
+	"revans_pocinje":     {"sr": "Počinje revanš.", "en": "The rematch begins."},
+	"seo_za_sto":         {"sr": "%s je seo za sto.", "en": "%s sat down at the table."},
+	"svi_za_stolom":      {"sr": "Svi su za stolom.", "en": "Everyone is at the table."},
+	"turnir_kolo":        {"sr": "Počinje %d. kolo, sto %d.", "en": "Round %d begins, table %d."},
+	"sto_zavrsio_cekaj":  {"sr": "Tvoj sto je završio kolo, sačekaj ostale stolove.", "en": "Your table has finished the round, wait for the other tables."},
+	"sto_zavrsio_kolo":   {"sr": "Sto je odigrao sve podele ovog kola.", "en": "The table has played all deals of this round."},
+	"turnir_zavrsen":     {"sr": "Turnir %s je završen.", "en": "Tournament %s is over."},
+	"nema_turnira":       {"sr": "turnir ne postoji", "en": "no such tournament"},
+	"nisi_na_turniru":    {"sr": "nisi prijavljen za ovaj turnir", "en": "you are not registered for this tournament"},
+	"turnir_nije_u_toku": {"sr": "turnir nije u toku", "en": "the tournament is not in progress"},
+	"sto_zavrsio":        {"sr": "sto je upravo završio kolo", "en": "the table has just finished the round"},
+
+	// veza, sobe i administrator
+	"nema_sobe":         {"sr": "soba %q ne postoji", "en": "room %q does not exist"},
+	"gledaj_sedis":      {"sr": "sediš za ovim stolom, ne možeš da ga gledaš", "en": "you are seated at this table and cannot watch it"},
+	"vec_sedis":         {"sr": "već sediš za ovim stolom", "en": "you already have a seat at this table"},
+	"nema_mesta":        {"sr": "za ovim stolom nema mesta za tebe", "en": "there is no seat for you at this table"},
+	"nepoznata_pravila": {"sr": "nepoznata pravila %q (dostupna: %s)", "en": "unknown rules %q (available: %s)"},
+	"gasi_se":           {"sr": "server se gasi, pokušaj ponovo posle restarta", "en": "the server is shutting down, try again after the restart"},
+	"gasi_se_pauza":     {"sr": "Server se gasi, sledeća podela posle restarta.", "en": "The server is shutting down, the next deal comes after the restart."},
+	"gasenje":           {"sr": "Server se gasi za %s. Ruka u toku se igra do kraja, nova se ne deli; meč se nastavlja posle restarta.", "en": "The server shuts down in %s. The hand in progress is played out, no new deal; the match continues after the restart."},
+	"nastavak_meca":     {"sr": "Svi su za stolom, meč se nastavlja.", "en": "Everyone is back, the match continues."},
+	"preuzima_mesto":    {"sr": "%s preuzima mesto %d.", "en": "%s takes seat %d."},
+	"admin_udaljen":     {"sr": "Administrator te je udaljio sa stola.", "en": "The administrator removed you from the table."},
+	"udaljen":           {"sr": "%s je udaljen sa stola, čeka se zamena.", "en": "%s was removed from the table, waiting for a replacement."},
+	"admin_prekid_ruke": {"sr": "Administrator je prekinuo ruku. Ruka se ne računa, deli se ponovo.", "en": "The administrator stopped the hand. It does not count, the cards are redealt."},
+	"admin_zatvorio":    {"sr": "Administrator je zatvorio sto.", "en": "The administrator closed the table."},
+	"previse_poruka":    {"sr": "Previše poruka, uspori.", "en": "Too many messages, slow down."},
+	"neispravna_poruka": {"sr": "Neispravna poruka.", "en": "Invalid message."},
+	"jezik_postavljen":  {"sr": "Poruke stižu na srpskom.", "en": "Messages are now in English."},
+	"nepoznat_jezik":    {"sr": "Nepoznat jezik %q (dostupni: %s).", "en": "Unknown language %q (available: %s)."},
+	"prijavi_se":        {"sr": "Prijavi se pre ulaska u igru.", "en": "Log in before joining a game."},
+	"previse_veza":      {"sr": "Previše otvorenih veza sa ove adrese.", "en": "Too many open connections from this address."},
+	"admin_token":       {"sr": "potreban je admin token", "en": "an admin token is required"},
+	"prazno_mesto":      {"sr": "mesto %d je prazno", "en": "seat %d is empty"},
+	"nema_naloga":       {"sr": "nalog %q ne postoji", "en": "account %q does not exist"},
+	"vec_sedi":          {"sr": "%s već sedi za ovim stolom", "en": "%s is already seated at this table"},
+	"ne_igra_se_ruka":   {"sr": "za stolom se ne igra ruka", "en": "no hand is being played at this table"},
+
+	// nalozi i HTTP API
+	"neispravan_zahtev":    {"sr": "neispravan zahtev", "en": "invalid request"},
+	"ocekuje_post":         {"sr": "očekuje se POST", "en": "POST expected"},
+	"greska_servera":       {"sr": "greška na serveru", "en": "server error"},
+	"ime_zauzeto":          {"sr": "korisničko ime je zauzeto", "en": "the username is taken"},
+	"pogresna_prijava":     {"sr": "pogrešno ime ili lozinka", "en": "wrong username or password"},
+	"losa_sesija":          {"sr": "sesija nije važeća", "en": "the session is not valid"},
+	"lose_ime":             {"sr": "ime mora imati od 3 do 20 znakova", "en": "the name must have 3 to 20 characters"},
+	"kratka_lozinka":       {"sr": "lozinka mora imati bar 8 znakova", "en": "the password must have at least 8 characters"},
+	"duga_lozinka":         {"sr": "lozinka sme imati najviše 72 bajta", "en": "the password may have at most 72 bytes"},
+	"previse_prijava":      {"sr": "previše pokušaja, pokušaj ponovo za minut", "en": "too many attempts, try again in a minute"},
+	"nepoznat_igrac":       {"sr": "nepoznat igrač", "en": "unknown player"},
+	"los_parametar":        {"sr": "neispravan parametar %s", "en": "invalid parameter %s"},
+	"nepoznato_poredjenje": {"sr": "nepoznato poređenje %q", "en": "unknown ranking %q"},
+	"prijave_zatvorene":    {"sr": "prijave za turnir su zatvorene", "en": "tournament registration is closed"},
+	"broj_igraca_turnira":  {"sr": "broj igrača mora biti deljiv sa tri", "en": "the number of players must be divisible by three"},
+	"samo_organizator":     {"sr": "samo organizator može da pokrene turnir", "en": "only the organizer can start the tournament"},
+	"previse_kola":         {"sr": "za %d igrača turnir može imati najviše %d kola bez ponovljenih susreta", "en": "with %d players the tournament can have at most %d rounds without repeated pairings"},
+	"turnir_granice":       {"sr": "broj kola mora biti 1–20, a podela po kolu 1–100", "en": "rounds must be 1–20 and deals per round 1–100"},
+
+	// chat
+	"chat":           {"sr": "%s: %s", "en": "%s: %s"},
+	"chat_dugacka":   {"sr": "Poruka može imati najviše %d znakova.", "en": "A message can have at most %d characters."},
+	"chat_iskljucen": {"sr": "Slobodan chat je isključen za ovaj sto, koristi brze poruke.", "en": "Free chat is off at this table, use quick messages."},
+	"chat_previse":   {"sr": "Previše poruka, sačekaj malo.", "en": "Too many messages, wait a moment."},
+	"chat_nepoznata": {"sr": "Nepoznata brza poruka.", "en": "Unknown quick message."},
+
+	// zahtev
+	"zahtev":           {"sr": "%s tvrdi da uzima još %d od %d štihova i otvara karte.", "en": "%s claims %d of the remaining %d tricks and shows their cards."},
+	"zahtev_ceka":      {"sr": "Zahtev već čeka odgovor.", "en": "A claim is already waiting for an answer."},
+	"zahtev_granice":   {"sr": "Možeš tražiti od 0 do %d štihova.", "en": "You can claim from 0 to %d tricks."},
+	"zahtev_pitanje":   {"sr": "Prihvataš li zahtev?", "en": "Do you accept the claim?"},
+	"zahtev_odbijen":   {"sr": "%s odbija zahtev, igra se dalje.", "en": "%s rejects the claim, play continues."},
+	"zahtev_prihvacen": {"sr": "%s prihvata zahtev.", "en": "%s accepts the claim."},
+	"zahtev_vazi":      {"sr": "Zahtev važi: %s uzima %d.", "en": "The claim stands: %s takes %d."},
+	"zahtev_ne_vazi":   {"sr": "Zahtev ne važi protiv najbolje odbrane (sigurno %d, tvrdio %d). Ruka se boduje po najboljoj igri, a zahtev je prijavljen administratoru.", "en": "The claim fails against the best defence (%d certain, %d claimed). The hand is scored by best play and the claim is reported to the administrator."},
+
+	// saveti
+	"savet_samo_trening":      {"sr": "Saveti su dostupni samo u sobama za trening.", "en": "Hints are available only in training rooms."},
+	"savet_nista":             {"sr": "Trenutno se od tebe ne traži nikakva odluka.", "en": "No decision is expected from you right now."},
+	"savet_adut_talon":        {"sr": "Adut možeš da promeniš kad vidiš talon.", "en": "You can change trumps after you see the talon."},
+	"savet_betl":              {"sr": "U svakoj boji imaš dovoljno niskih karata da se podvučeš ispod tuđih — betl.", "en": "Every suit has enough low cards to duck under the others — misère."},
+	"savet_sans":              {"sr": "I bez aduta očekuješ oko %.1f štihova — sans.", "en": "Even without trumps you expect about %.1f tricks — no trumps."},
+	"savet_igra":              {"sr": "Sa adutom %s očekuješ oko %.1f štihova i bez talona — igra.", "en": "With %s as trumps you expect about %.1f tricks even without the talon — game."},
+	"savet_licitiraj":         {"sr": "Sa adutom %s očekuješ oko %.1f štihova, a talon u proseku donese još %.1f. Licitiraj najniže što smeš.", "en": "With %s as trumps you expect about %.1f tricks, and the talon adds %.1f on average. Bid the lowest you may."},
+	"savet_pas":               {"sr": "Očekuješ oko %.1f štihova (adut %s), a za prolaz treba %d — bolje je reći pas.", "en": "You expect about %.1f tricks (trumps %s) and need %d to make it — better pass."},
+	"savet_adut":              {"sr": "Sa adutom %s ruka vredi najviše, oko %.1f štihova.", "en": "The hand is worth most with %s as trumps, about %.1f tricks."},
+	"savet_skart_bez_talona":  {"sr": "Škart se bira kad je talon u ruci.", "en": "The discard is chosen once the talon is in hand."},
+	"savet_skart":             {"sr": "Odbaci %s i %s: ostatak ruke vredi oko %.1f štihova.", "en": "Discard %s and %s: the rest of the hand is worth about %.1f tricks."},
+	"savet_rekontra":          {"sr": "Ruka nosi oko %.1f štihova, štih više od potrebnih %d.", "en": "Your hand takes about %.1f tricks, one more than the %d you need."},
+	"savet_bez_rekontre":      {"sr": "Ruka nosi oko %.1f štihova; bez štiha viška preko %d rekontra je rizična.", "en": "Your hand takes about %.1f tricks; without a trick to spare over %d a redouble is risky."},
+	"savet_rekontra_betl":     {"sr": "Nijedan štih ne moraš da uzmeš, rekontra je sigurna.", "en": "You never have to take a trick, the redouble is safe."},
+	"savet_bez_rekontre_betl": {"sr": "Betl nije siguran, rekontra bi bila rizična.", "en": "The misère is not safe, a redouble would be risky."},
+	"savet_prati_betl":        {"sr": "Betl se prati: pratilac ništa ne rizikuje.", "en": "Always defend a misère: the defender risks nothing."},
+	"savet_prati":             {"sr": "U odbrani očekuješ oko %.1f štihova, a pratilac mora da uzme %d.", "en": "You expect about %.1f tricks in defence, and a defender must take %d."},
+	"savet_ne_prati":          {"sr": "U odbrani očekuješ samo oko %.1f štihova, a pratilac mora da uzme %d.", "en": "You expect only about %.1f tricks in defence, but a defender must take %d."},
+	"savet_kontra_betl":       {"sr": "Betl se kontrira samo kad znaš da deklarant mora da uzme štih.", "en": "Double a misère only when you know the declarer must take a trick."},
+	"savet_kontra":            {"sr": "U odbrani očekuješ oko %.1f štihova; uz drugog branioca deklarant teško uzima %d.", "en": "You expect about %.1f tricks in defence; with your partner the declarer will struggle to take %d."},
+	"savet_dalje":             {"sr": "U odbrani očekuješ samo oko %.1f štihova, kontra bi bila rizična.", "en": "You expect only about %.1f tricks in defence, a double would be risky."},
+	"savet_jedina_karta":      {"sr": "To je jedina karta koju smeš da baciš.", "en": "That is the only card you may play."},
+	"savet_karta":             {"sr": "Karta %s je bila najbolja u %d od %d mogućih rasporeda karata koje ne vidiš.", "en": "%s was the best card in %d of %d possible layouts of the cards you cannot see."},
+	"savet_nosi":              {"sr": "%s je najniža karta koja za sada nosi štih.", "en": "%s is the lowest card that currently wins the trick."},
+	"savet_najniza":           {"sr": "Nema sigurnog štiha, baci najnižu kartu (%s).", "en": "No sure trick, play your lowest card (%s)."},
+
+	// vežbanje
+	"nema_ruke":              {"sr": "nema te ruke", "en": "no such hand"},
+	"vezba_bez_ruke":         {"sr": "pošalji ruku ili zapis", "en": "send a hand or a record"},
+	"vezba_mesto":            {"sr": "mesto mora biti 0–2", "en": "the seat must be 0–2"},
+	"vezba_podela":           {"sr": "podela mora imati svih 32 karte, svaku po jednom", "en": "the deal must have all 32 cards, each once"},
+	"vezba_prvi":             {"sr": "prvi mora biti mesto 0–2", "en": "the first player must be seat 0–2"},
+	"vezba_od_stiha_granice": {"sr": "od_stiha mora biti od 0 do 10", "en": "od_stiha must be from 0 to 10"},
+	"vezba_bez_ugovora":      {"sr": "zapis nema ugovor, vežba može samo od licitacije", "en": "the record has no contract, practice can only start from the auction"},
+	"vezba_malo_stihova":     {"sr": "zapis nema odigranih %d štihova", "en": "the record does not have %d tricks played"},
+	"vezba_stih":             {"sr": "%d. štih: %s", "en": "trick %d: %s"},
+	"vezba_skart":            {"sr": "škart mora biti dve karte iz ruke deklaranta i talona", "en": "the discard must be two cards from the declarer's hand and the talon"},
+	"vezba_kontra":           {"sr": "kontra %d nije dozvoljena ovim pravilima", "en": "double level %d is not allowed by these rules"},
+	"vezba_pratili":          {"sr": "u zapisu prate samo branioci, i bar jedan mora da prati", "en": "only defenders can follow in the record, and at least one must"},
+	"vezba_adut":             {"sr": "nepoznat adut %q", "en": "unknown trump suit %q"},
+	"vezba_od_licitacije":    {"sr": "Vežba počinje od licitacije.", "en": "Practice starts from the auction."},
+	"vezba_od_stiha":         {"sr": "Vežba počinje od %d. štiha, sa ugovorom iz originalne ruke.", "en": "Practice starts from trick %d, with the contract of the original hand."},
+	"vezba_nije_odigrana":    {"sr": "Ruka nije odigrana: svi su rekli pas ili igra nije važila.", "en": "The hand was not played: everyone passed or the contract did not count."},
+	"vezba_ishod":            {"sr": "U vežbi %s.", "en": "In practice %s."},
+	"vezba_original":         {"sr": "U originalu %s.", "en": "In the original %s."},
+	"vezba_najbolje":         {"sr": "Sa otvorenim kartama i najboljom igrom svih, mesto %d uzima %d.", "en": "With all cards open and best play by everyone, seat %d takes %d."},
+	"ishod_prolazi":          {"sr": "mesto %d igra %s, uzima %d štihova i prolazi", "en": "seat %d plays %s, takes %d tricks and makes it"},
+	"ishod_pada":             {"sr": "mesto %d igra %s, uzima %d štihova i pada", "en": "seat %d plays %s, takes %d tricks and goes down"},
+}
+
+func init() {
+	// brze poruke chata su deo kataloga
+	for kod, prevodi := range BrzePoruke {
+		Katalog["brza_"+kod] = prevodi
+	}
+	for _, prevodi := range Katalog {
+		prevodi["sr-Cyrl"] = cirilica(prevodi["sr"])
+	}
+}
+
+var latinicaUCirilicu = map[string]string{
+	"lj": "љ", "nj": "њ", "dž": "џ", "Lj": "Љ", "LJ": "Љ", "Nj": "Њ", "NJ": "Њ", "Dž": "Џ", "DŽ": "Џ",
+	"a": "а", "b": "б", "c": "ц", "č": "ч", "ć": "ћ", "d": "д", "đ": "ђ", "e": "е", "f": "ф",
+	"g": "г", "h": "х", "i": "и", "j": "ј", "k": "к", "l": "л", "m": "м", "n": "н", "o": "о",
+	"p": "п", "r": "р", "s": "с", "š": "ш", "t": "т", "u": "у", "v": "в", "z": "з", "ž": "ж",
+	"A": "А", "B": "Б", "C": "Ц", "Č": "Ч", "Ć": "Ћ", "D": "Д", "Đ": "Ђ", "E": "Е", "F": "Ф",
+	"G": "Г", "H": "Х", "I": "И", "J": "Ј", "K": "К", "L": "Л", "M": "М", "N": "Н", "O": "О",
+	"P": "П", "R": "Р", "S": "С", "Š": "Ш", "T": "Т", "U": "У", "V": "В", "Z": "З", "Ž": "Ж",
+}
+
+// cirilica preslovljava srpsku latinicu; fmt glagoli (%d, %.1f, %[2]s) ostaju
+func cirilica(s string) string {
+	var b strings.Builder
+	r := []rune(s)
+	for i := 0; i < len(r); i++ {
+		if r[i] == '%' {
+			j := i + 1
+			for j < len(r) && !strings.ContainsRune("vsdqf%", r[j]) {
+				j++
+			}
+			b.WriteString(string(r[i:min(j+1, len(r))]))
+			i = j
+			continue
+		}
+		if i+1 < len(r) {
+			if c, ok := latinicaUCirilicu[string(r[i:i+2])]; ok {
+				b.WriteString(c)
+				i++
+				continue
+			}
+		}
+		if c, ok := latinicaUCirilicu[string(r[i])]; ok {
+			b.WriteString(c)
+		} else {
+			b.WriteRune(r[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ==== Rejting ====
+// Elo za tri igrača: meč se razlaže na tri dvoboja. U svakom dvoboju bolji
+// saldo nosi pobedu (isti saldo je remi), a K se deli na dva protivnika.
+const (
+	pocetniRejting = 1500.0
+	rejtingK       = 32.0
+)
+
+var rejtingPutanja = "rejting.json"
+
+type PromenaRejtinga struct {
+	Vreme      time.Time `json:"vreme"`
+	Pre        float64   `json:"pre"`
+	Posle      float64   `json:"posle"`
+	Saldo      int       `json:"saldo"`
+	Protivnici [2]int    `json:"protivnici"`
+}
+
+type Rejting struct {
+	Igrac    int               `json:"igrac"`
+	Vrednost float64           `json:"vrednost"`
+	Meceva   int               `json:"meceva"`
+	Istorija []PromenaRejtinga `json:"istorija"`
+}
+
+type ratingStore struct {
+	mu     sync.Mutex
+	path   string
+	igraci map[int]*Rejting
+}
+
+var rejtinzi *ratingStore
+
+func loadRatings(path string) (*ratingStore, error) {
+	s := &ratingStore{path: path, igraci: map[int]*Rejting{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lista []*Rejting
+	if err := json.Unmarshal(data, &lista); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, r := range lista {
+		s.igraci[r.Igrac] = r
+	}
+	return s, nil
+}
+
+// save mora da se zove sa zaključanim s.mu
+func (s *ratingStore) save() error {
+	lista := make([]*Rejting, 0, len(s.igraci))
+	for _, r := range s.igraci {
+		lista = append(lista, r)
+	}
+	sort.Slice(lista, func(i, j int) bool { return lista[i].Igrac < lista[j].Igrac })
+	data, err := json.MarshalIndent(lista, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// mora da se zove sa zaključanim s.mu
+func (s *ratingStore) za(id int) *Rejting {
+	r, ok := s.igraci[id]
+	if !ok {
+		r = &Rejting{Igrac: id, Vrednost: pocetniRejting}
+		s.igraci[id] = r
+	}
+	return r
+}
+
+// vrednost vraća trenutni rejting igrača (početni ako još nije odigrao meč)
+func (s *ratingStore) vrednost(id int) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.igraci[id]; ok {
+		return r.Vrednost
+	}
+	return pocetniRejting
+}
+
+// upisi ažurira rejtinge posle završenog meča; korisnici su ID-jevi naloga po mestima
+func (s *ratingStore) upisi(korisnici [3]int, saldo [3]int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pre [3]float64
+	for i, id := range korisnici {
+		pre[i] = s.za(id).Vrednost
+	}
+	sada := time.Now()
+	for i, id := range korisnici {
+		delta := 0.0
+		var protivnici [2]int
+		k := 0
+		for j := range korisnici {
+			if j == i {
+				continue
+			}
+			protivnici[k] = korisnici[j]
+			k++
+			ocekivano := 1 / (1 + math.Pow(10, (pre[j]-pre[i])/400))
+			stvarno := 0.5
+			if saldo[i] > saldo[j] {
+				stvarno = 1
+			} else if saldo[i] < saldo[j] {
+				stvarno = 0
+			}
+			delta += rejtingK / 2 * (stvarno - ocekivano)
+		}
+		r := s.za(id)
+		r.Vrednost = math.Round((pre[i]+delta)*10) / 10
+		r.Meceva++
+		r.Istorija = append(r.Istorija, PromenaRejtinga{
+			Vreme:      sada,
+			Pre:        pre[i],
+			Posle:      r.Vrednost,
+			Saldo:      saldo[i],
+			Protivnici: protivnici,
+		})
+	}
+	return s.save()
+}
+
+// GET /api/players/{id}/rating
+func handleRating(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || korisnici.get(id) == nil {
+		jsonError(w, r, http.StatusNotFound, tr("nepoznat_igrac"))
+		return
+	}
+	rejtinzi.mu.Lock()
+	odgovor := Rejting{Igrac: id, Vrednost: pocetniRejting, Istorija: []PromenaRejtinga{}}
+	if rj, ok := rejtinzi.igraci[id]; ok {
+		odgovor = *rj
+		odgovor.Istorija = append([]PromenaRejtinga{}, rj.Istorija...)
+	}
+	rejtinzi.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":       id,
+		"ime":      korisnici.get(id).Ime,
+		"rejting":  odgovor.Vrednost,
+		"meceva":   odgovor.Meceva,
+		"istorija": odgovor.Istorija,
+	})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"slices"
+
+	"multiplayer-game/preferans"
+)
+
+// ==== Saveti (trening) ====
+// U sobi za trening (/ws?trening=1) igrač može da pošalje {"type":"hint"} i
+// dobije predlog za odluku koja se od njega trenutno traži. Savet se računa
+// samo iz onoga što igrač sme da vidi, a broj saveta po mestu se upisuje uz ruku.
+
+// savet šalje igraču predlog za trenutnu fazu; zove se sa zaključanim r.mu
+func (r *Room) savet(p *Player) {
+	if !r.trening {
+		r.greska(p, tr("savet_samo_trening"))
+		return
+	}
+	faza, s, ok := r.savetZa(p)
+	if !ok {
+		r.posalji(p, map[string]any{
+			"type":    "hint",
+			"message": tr("savet_nista"),
+		})
+		return
+	}
+	r.saveti[p.id]++
+	msg := map[string]any{
+		"type":    "hint",
+		"faza":    faza,
+		"predlog": s.Predlog,
+		"message": objasnjenje(s),
+	}
+	if faza == "igra" {
+		// karta ide i kroz vidljivost kao svaka druga
+		msg["card"] = s.Predlog
+	}
+	if s.Karte != nil {
+		msg["cards"] = s.Karte
+	}
+	r.posalji(p, msg)
+}
+
+// savetZa bira šta se savetuje prema tome šta igrač sada treba da uradi
+func (r *Room) savetZa(p *Player) (string, preferans.Savet, bool) {
+	deklarant := p == r.highestBidder
+	adut := preferans.AdutIzStila(r.adut)
+	switch {
+	case r.faza == fazaIgra:
+		if r.zahtev != nil || r.igra.NaPotezu != p.id {
+			return "", preferans.Savet{}, false
+		}
+		najvise := r.highestBid != preferans.Betl
+		nepoznate, otvorene := r.nepoznateKarte(p)
+		return "igra", preferans.SavetKarta(r.igra, p.id, r.highestBidder.id, najvise, nepoznate, otvorene, nil), true
+	case r.faza == fazaPracenje && r.pitanZaPracenje == p.id:
+		return "pracenje", preferans.SavetPracenje(p.cards, adut, r.highestBid, r.rules.PratilacMora), true
+	case r.faza == fazaKontra && r.kontraNaRedu == p.id && deklarant:
+		return "kontra", preferans.SavetRekontra(p.cards, adut, r.highestBid), true
+	case r.faza == fazaKontra && r.kontraNaRedu == p.id:
+		return "kontra", preferans.SavetKontra(p.cards, adut, r.highestBid), true
+	case r.faza == fazaSkart && deklarant:
+		return "skart", preferans.SavetSkart(p.cards, adut), true
+	case r.faza == fazaAdut && deklarant:
+		// talon je već viđen, pa se adut i škart biraju iz svih dvanaest karata
+		ruka := append(slices.Clone(p.cards), r.talon...)
+		s := preferans.SavetAdut(ruka)
+		skart := preferans.SavetSkart(ruka, []rune(s.Predlog)[0])
+		s.Karte = skart.Karte
+		s.Kod, s.Param = kodSpoj, []any{objasnjenje(s), objasnjenje(skart)}
+		return "adut", s, true
+	case r.faza == fazaPotvrda && deklarant:
+		s := preferans.SavetAdut(p.cards)
+		if preferans.SaTalonom(r.highestBid) {
+			s.Kod, s.Param = kodSpoj, []any{objasnjenje(s), tr("savet_adut_talon")}
+		}
+		return "potvrda", s, true
+	case r.faza == fazaLicitacija && r.licitacija.NaPotezu == p.id:
+		return "licitacija", preferans.SavetLicitacija(p.cards, r.licitacija.Dozvoljene(p.id)), true
+	}
+	return "", preferans.Savet{}, false
+}
+
+// objasnjenje je poruka uz savet
+func objasnjenje(s preferans.Savet) *Poruka {
+	return tr(s.Kod, s.Param...)
+}
+
+// nepoznateKarte su karte koje igrač ne vidi, a mogu biti u tuđim rukama, i
+// ruke koje su svima otvorene; savet za kartu ne sme da zna ništa više
+func (r *Room) nepoznateKarte(p *Player) ([]string, map[int][]string) {
+	poznate := map[string]bool{}
+	for _, c := range p.cards {
+		poznate[c] = true
+	}
+	for c := range r.odigrane {
+		poznate[c] = true
+	}
+	if p == r.highestBidder {
+		for _, c := range r.skart {
+			poznate[c] = true
+		}
+	}
+	otvorene := map[int][]string{}
+	if o := r.otkrivena; o != nil && o != p {
+		otvorene[o.id] = o.cards
+		for _, c := range o.cards {
+			poznate[c] = true
+		}
+	}
+	nepoznate := []string{}
+	for _, c := range preferans.Deck {
+		if !poznate[c] {
+			nepoznate = append(nepoznate, c)
+		}
+	}
+	return nepoznate, otvorene
+}
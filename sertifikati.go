@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ==== TLS ====
+// Sertifikat se čita pri svakom novom TLS rukovanju iz memorije, a fajlovi se
+// proveravaju na svakih proveraSertifikata. Kad ih ACME klijent zameni, novi
+// sertifikat važi bez restarta; ako novi fajlovi ne valjaju, ostaje stari.
+const proveraSertifikata = 30 * time.Second
+
+type sertifikati struct {
+	sertPutanja, kljucPutanja string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	izmenjen time.Time // novije vreme izmene od dva fajla
+}
+
+func ucitajSertifikate(sertPutanja, kljucPutanja string) (*sertifikati, error) {
+	s := &sertifikati{sertPutanja: sertPutanja, kljucPutanja: kljucPutanja}
+	if err := s.ucitaj(); err != nil {
+		return nil, err
+	}
+	go s.prati()
+	return s, nil
+}
+
+func (s *sertifikati) izmenjeni() (time.Time, error) {
+	var najnovije time.Time
+	for _, p := range []string{s.sertPutanja, s.kljucPutanja} {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if fi.ModTime().After(najnovije) {
+			najnovije = fi.ModTime()
+		}
+	}
+	return najnovije, nil
+}
+
+func (s *sertifikati) ucitaj() error {
+	izmenjen, err := s.izmenjeni()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(s.sertPutanja, s.kljucPutanja)
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+	s.mu.Lock()
+	s.cert = &cert
+	s.izmenjen = izmenjen
+	s.mu.Unlock()
+	return nil
+}
+
+// prati ponovo učitava sertifikat kad se fajlovi promene
+func (s *sertifikati) prati() {
+	for range time.Tick(proveraSertifikata) {
+		izmenjen, err := s.izmenjeni()
+		s.mu.RLock()
+		isti := izmenjen.Equal(s.izmenjen)
+		s.mu.RUnlock()
+		if err != nil || isti {
+			continue
+		}
+		if err := s.ucitaj(); err != nil {
+			// ACME klijent možda još piše ključ; pokušaćemo ponovo na sledećoj proveri
+			slog.Warn("novi sertifikat nije učitan, ostaje stari", "greska", err)
+			continue
+		}
+		slog.Info("sertifikat ponovo učitan", "fajl", s.sertPutanja)
+	}
+}
+
+func (s *sertifikati) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cert, nil
+}
+
+// preusmeriNaHTTPS je handler za HTTP slušač: svaki zahtev šalje na isti put preko HTTPS-a
+func preusmeriNaHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if _, port, _ := net.SplitHostPort(konfig.Adresa); port != "" && port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+}
+
+// prekoHTTPS javlja da li je klijent došao preko TLS-a, direktno ili kroz proxy
+func prekoHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// ==== Konfiguracija klijenta ====
+// GET /config.js govori klijentu na koji websocket da se poveže, da game.js ne
+// bi pogađao adresu. ws-url iz konfiguracije ima prednost (npr. iza proxy-ja).
+func handleConfigJS(w http.ResponseWriter, r *http.Request) {
+	ws := konfig.WSURL
+	if ws == "" {
+		sema := "ws"
+		if prekoHTTPS(r) {
+			sema = "wss"
+		}
+		ws = sema + "://" + r.Host + "/ws"
+	}
+	data, _ := json.Marshal(map[string]string{"ws": ws})
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", kesProveri)
+	fmt.Fprintf(w, "window.WSPREF = %s;\n", data)
+}
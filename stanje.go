@@ -0,0 +1,289 @@
+package main
+
+import (
+	"maps"
+	"slices"
+
+	"multiplayer-game/preferans"
+)
+
+// ==== Stanje stola ====
+// Sto je uvek u tačno jednoj fazi podele, i faza se menja samo u handleru koji
+// je pomera dalje. Posle svake obrađene poruke svako za stolom dobija poruku
+// state sa celim stanjem koje sme da vidi: fazu, ko je na potezu, šta sme da
+// pošalje, licitaciju, ugovor, kontru, štih na stolu, štihove i rezultat meča.
+// Klijent tako ne mora da sklapa stanje iz your_turn, potvrdi_igru, biraj_stil,
+// discard_talon, prati_prompt, kontra_prompt i turn, a {"type":"stanje"} ga traži ponovo.
+//
+// Svaki poziv na odluku i svako stanje nose i tačne opcije iz moguce: ponude,
+// adute, broj karata za škart, odgovore i karte koje sme da baci. Klijent i
+// botovi biraju jednu od ponuđenih vrednosti i ne moraju da znaju pravila, a
+// handler prihvata samo te vrednosti.
+
+// Faza je deo podele u kome je sto
+type Faza int
+
+const (
+	fazaCekanje    Faza = iota // sto nije pun ili nova podela još nije počela
+	fazaLicitacija             // igrači licitiraju redom
+	fazaPotvrda                // licitacija je gotova, deklarant potvrđuje igru
+	fazaAdut                   // deklarant je video talon i bira adut
+	fazaSkart                  // talon je u ruci deklaranta, čeka se škart
+	fazaPracenje               // protivnici redom kažu da li prate
+	fazaKontra                 // kontra, rekontra i subkontra, jedan po jedan
+	fazaIgra                   // bacaju se karte
+	fazaKrajMeca               // meč je završen, čeka se revanš
+)
+
+var naziviFaza = [...]string{"cekanje", "licitacija", "potvrda", "adut", "skart", "pracenje", "kontra", "igra", "kraj_meca"}
+
+func (f Faza) String() string {
+	return naziviFaza[f]
+}
+
+// MarshalText daje naziv faze u JSON-u
+func (f Faza) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// Ponuda je jedan korak licitacije
+type Ponuda struct {
+	Mesto  int `json:"mesto"`
+	Ponuda int `json:"ponuda"` // preferans.Pas kad igrač kaže dalje
+}
+
+// naPotezu su mesta od kojih sto čeka odgovor; zove se sa zaključanim r.mu
+func (r *Room) naPotezu() []int {
+	out := []int{}
+	if r.pauza || len(r.players) < 3 {
+		return out
+	}
+	switch r.faza {
+	case fazaLicitacija:
+		out = append(out, r.licitacija.NaPotezu)
+	case fazaPotvrda, fazaAdut, fazaSkart:
+		out = append(out, r.highestBidder.id)
+	case fazaPracenje:
+		out = append(out, r.pitanZaPracenje)
+	case fazaKontra:
+		out = append(out, r.kontraNaRedu)
+	case fazaIgra:
+		if r.zahtev == nil {
+			out = append(out, r.igra.NaPotezu)
+			break
+		}
+		for _, p := range r.players {
+			if p != r.highestBidder && !r.zahtev.prihvatili[p.id] {
+				out = append(out, p.id)
+			}
+		}
+	case fazaKrajMeca:
+		for _, p := range r.players {
+			if !r.match.revans[p.id] {
+				out = append(out, p.id)
+			}
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// akcije su tipovi poruka koje izvrsi sada prihvata od p
+func (r *Room) akcije(p *Player) []string {
+	out := []string{}
+	if p.gledalac {
+		return out
+	}
+	if r.faza == fazaIgra && r.zahtev == nil && p == r.highestBidder {
+		// deklarant sme da postavi zahtev i kad nije na potezu
+		out = append(out, "claim")
+	}
+	if !slices.Contains(r.naPotezu(), p.id) {
+		return out
+	}
+	switch r.faza {
+	case fazaLicitacija:
+		broj, igra := false, false
+		for _, v := range r.licitacija.Dozvoljene(p.id) {
+			broj = broj || (v >= preferans.MinBroj && v <= preferans.MaxBroj)
+			igra = igra || v > preferans.MaxBroj
+		}
+		if broj {
+			out = append(out, "bid")
+		}
+		if igra {
+			out = append(out, "igra")
+		}
+		out = append(out, "pass")
+	case fazaPotvrda:
+		out = append(out, "potvrdi_igru")
+	case fazaAdut:
+		out = append(out, "stil_odabran")
+	case fazaSkart:
+		out = append(out, "odbaci_karte")
+	case fazaPracenje:
+		out = append(out, "prati")
+	case fazaKontra:
+		out = append(out, "kontra_odgovor")
+	case fazaIgra:
+		if r.zahtev != nil {
+			return append(out, "claim_odgovor")
+		}
+		out = append(out, "baci_kartu")
+	case fazaKrajMeca:
+		return append(out, "revans")
+	}
+	if r.trening {
+		out = append(out, "hint")
+	}
+	return out
+}
+
+// moguce su tačne opcije odluke koju p sada donosi, u poljima koja idu uz poziv
+// i uz state; zove se sa zaključanim r.mu
+func (r *Room) moguce(p *Player) map[string]any {
+	out := map[string]any{}
+	if p.gledalac {
+		return out
+	}
+	if r.faza == fazaIgra && r.zahtev == nil && p == r.highestBidder {
+		// zahtev: koliko još štihova deklarant sme da tvrdi
+		out["zahtev_do"] = 10 - r.igra.Odigrano
+	}
+	if !slices.Contains(r.naPotezu(), p.id) {
+		return out
+	}
+	switch r.faza {
+	case fazaLicitacija:
+		out["ponude"] = r.licitacija.Dozvoljene(p.id)
+	case fazaPotvrda:
+		out["aduti"] = r.adutiPotvrde()
+	case fazaAdut:
+		out["aduti"] = sviAduti()
+	case fazaSkart:
+		out["broj"] = len(p.cards) - 10
+	case fazaPracenje:
+		out["odgovori"] = []bool{true, false}
+	case fazaKontra:
+		out["odgovori"] = []bool{false}
+		if r.rules.SmeKontru(r.kontraStatus) {
+			out["odgovori"] = []bool{true, false}
+			out["nivo"] = r.kontraStatus + 1
+		}
+	case fazaIgra:
+		if r.zahtev != nil {
+			out["odgovori"] = []bool{true, false}
+		} else {
+			out["legalne"] = r.igra.Legalne()
+		}
+	case fazaKrajMeca:
+		out["odgovori"] = []bool{true, false}
+	}
+	return out
+}
+
+// ponudjeno javlja da li je vrednost iz poruke tačno jedna od opcija koje p ima
+// u moguce pod poljem; zove se sa zaključanim r.mu
+func (r *Room) ponudjeno(p *Player, polje string, v any) bool {
+	switch opcije := r.moguce(p)[polje].(type) {
+	case []bool:
+		b, ok := v.(bool)
+		return ok && slices.Contains(opcije, b)
+	case []string:
+		s, ok := v.(string)
+		return ok && slices.Contains(opcije, s)
+	}
+	return false
+}
+
+// adutiPotvrde su aduti koje deklarant bira uz potvrdi_igru: samo u igri bez
+// talona sa adutom, inače je lista prazna i vrednost se ne šalje
+func (r *Room) adutiPotvrde() []string {
+	if preferans.SaTalonom(r.highestBid) || !preferans.BiraAdut(r.highestBid) {
+		return []string{}
+	}
+	return sviAduti()
+}
+
+// sviAduti su boje u obliku koji prima preferans.AdutIzStila
+func sviAduti() []string {
+	out := []string{}
+	for _, b := range preferans.Suits {
+		out = append(out, string(b))
+	}
+	return out
+}
+
+// pozovi šalje igraču poziv na odluku zajedno sa tačnim opcijama; zove se sa zaključanim r.mu
+func (r *Room) pozovi(p *Player, msg map[string]any) {
+	maps.Copy(msg, r.moguce(p))
+	r.posalji(p, msg)
+}
+
+// stanje je poruka state za p; sadrži samo karte koje p sme da vidi
+func (r *Room) stanje(p *Player) map[string]any {
+	msg := map[string]any{
+		"type":       "state",
+		"faza":       r.faza,
+		"na_potezu":  r.naPotezu(),
+		"akcije":     r.akcije(p),
+		"podela":     r.podela(),
+		"licitacija": r.ponude,
+		"kontra":     r.kontraStatus,
+	}
+	if r.pauza {
+		msg["pauza"] = true
+	}
+	deklarant := !p.gledalac && p == r.highestBidder
+	if !p.gledalac {
+		msg["cards"] = p.cards
+	}
+	if r.highestBidder != nil {
+		// za vreme licitacije ovo je najviša ponuda do sada
+		msg["ugovor"] = map[string]any{
+			"deklarant": r.highestBidder.id,
+			"ponuda":    r.highestBid,
+			"adut":      r.adut,
+		}
+	}
+	if r.talonUzet && (r.rules.TalonOtkriven || deklarant) {
+		msg["talon"] = r.talon
+	}
+	if deklarant && r.skart != nil {
+		msg["skart"] = r.skart
+	}
+	if r.otkrivena != nil {
+		msg["otvorene"] = r.otkrivena.cards
+	}
+	if r.igra != nil {
+		msg["stih"] = append([]string{}, r.igra.Stih...)
+		msg["vodi"] = r.igra.Vodi
+		msg["stihovi"] = r.igra.Stihovi
+	}
+	if r.zahtev != nil {
+		msg["zahtev"] = r.zahtev.stihova
+	}
+	if r.match != nil {
+		msg["mec"] = r.match.stanje()
+	}
+	maps.Copy(msg, r.moguce(p))
+	return msg
+}
+
+// posaljiStanje šalje stanje jednom igraču ili gledaocu; zove se sa zaključanim r.mu
+func (r *Room) posaljiStanje(p *Player) {
+	if p.bot {
+		return
+	}
+	r.posalji(p, r.stanje(p))
+}
+
+// javiStanje šalje svakome za stolom njegovo stanje; zove se sa zaključanim r.mu
+func (r *Room) javiStanje() {
+	for _, p := range r.players {
+		r.posaljiStanje(p)
+	}
+	for _, g := range r.gledaoci {
+		r.posaljiStanje(g)
+	}
+}
@@ -1,46 +1,115 @@
 let mycards = [];
 
-const socket = new WebSocket("ws://localhost:8080/ws");
+let socket = null;
 
 let myPlayerId = null;
 
-socket.onmessage = function(event) {
-	const data = JSON.parse(event.data);
-   // Ako je tvoj red za licitaciju i server šalje dostupne akcije
-  if (data.type === "your_turn" && data.actions) {
-    const container = document.getElementById("actions");
-    container.innerHTML = ""; // očisti prethodne dugmiće
-
-    data.actions.forEach(action => {
-      const btn = document.createElement("button");
-      btn.textContent = action;
-      btn.onclick = () => {
-        socket.send(JSON.stringify({ type: "bid", value: action }));
-        container.innerHTML = ""; // skloni dugmiće nakon klika
-      };
-      container.appendChild(btn);
+// soba iz server_shutdown poruke; posle restarta se vraćamo na isto mesto
+let mojaSoba = null;
+let pokusajaPovratka = 0;
+
+// Igra se otvara tek posle prijave, jer server traži kolačić sesije za /ws
+async function proveriSesiju() {
+    const res = await fetch("/api/me");
+    if (res.ok) {
+        ulogovan(await res.json());
+    }
+}
+
+document.getElementById("prijava").addEventListener("submit", async (e) => {
+    e.preventDefault();
+    const akcija = e.submitter ? e.submitter.dataset.akcija : "login";
+    const res = await fetch("/api/" + akcija, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+            ime: document.getElementById("ime").value,
+            lozinka: document.getElementById("lozinka").value
+        })
+    });
+    const data = await res.json();
+    if (!res.ok) {
+        document.getElementById("prijava-greska").textContent = data.error;
+        return;
+    }
+    ulogovan(data);
+});
+
+async function ulogovan(korisnik) {
+    document.getElementById("prijava").style.display = "none";
+    document.getElementById("korisnik").textContent = "Prijavljen: " + korisnik.ime;
+    povezi();
+    const res = await fetch("/api/players/" + korisnik.id + "/rating");
+    if (res.ok) {
+        const r = await res.json();
+        document.getElementById("korisnik").textContent =
+            "Prijavljen: " + korisnik.ime + " (rejting " + r.rejting + ", mečeva " + r.meceva + ")";
+    }
+}
+
+// prikaziSto ispisuje igrače za stolom sa njihovim rejtingom
+function prikaziSto(imena, rejting) {
+    const sto = document.getElementById("sto");
+    sto.innerHTML = "";
+    imena.forEach((ime, i) => {
+        if (!ime) {
+            return;
+        }
+        const red = document.createElement("div");
+        red.textContent = ime + (rejting ? " — " + rejting[i] : "");
+        sto.appendChild(red);
     });
-  }
+}
+
+function povezi(soba) {
+    const upit = soba ? "?soba=" + encodeURIComponent(soba) : "";
+    // adresu daje server u /config.js; bez nje se uzima ista adresa kao stranica
+    const adresa = window.WSPREF ? window.WSPREF.ws : (location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/ws";
+    socket = new WebSocket(adresa + upit);
+    socket.onmessage = onMessage;
+    socket.onclose = (e) => {
+        // 1012: server se restartuje; pokušavaj dok ne proradi, najviše 40 puta
+        if (e.code === 1012 || (pokusajaPovratka > 0 && pokusajaPovratka < 40)) {
+            pokusajaPovratka++;
+            setTimeout(() => povezi(mojaSoba), 3000);
+        }
+    };
+}
+
+function prikaziPoruku(tekst) {
+    document.getElementById("messages").textContent = tekst;
+}
+
+proveriSesiju();
+
+function onMessage(event) {
+	const data = JSON.parse(event.data);
+	// stanje stola stiže posle svake promene, sa opcijama za odluku koja se traži
+	if (data.type === "state") {
+		prikaziOpcije(data);
+	}
+	if (data.type === "igraci" || data.type === "kraj_meca") {
+		prikaziSto(data.imena, data.rejting);
+	}
+	if (data.type === "server_shutdown") {
+		mojaSoba = data.soba;
+		prikaziPoruku(data.message);
+	}
+	if (data.type === "error" && pokusajaPovratka > 0) {
+		// server radi, ali nas ne vraća za sto; nema smisla pokušavati dalje
+		pokusajaPovratka = 40;
+		prikaziPoruku(data.message);
+	}
 	if (data.type === "you_are") {
+		pokusajaPovratka = 0;
 		myPlayerId = data.id;
 		console.log("Ja sam igrač", myPlayerId);
 	}
-
-	if (data.type === "auction_turn" && myPlayerId !== null) {
-		if (data.player === myPlayerId) {
-			showAuctionButtons();
-		}
-	}
     if (data.type === "your_cards") {
         mycards = data.cards;
         console.log("Primljene karte:", mycards);
     }
-    if (data.type === "auction_start" || data.type === "auction_turn") {
-    if (data.player === myPlayerId) { // ← koristiš ID svog igrača
-        showAuctionButtons();
-    }
 }
-};
 
 document.getElementById("show-cards-btn").addEventListener("click", () => {
     if (mycards.length === 0) {
@@ -76,22 +145,59 @@ function cardToFilename(card) {
     return `${rank}_${suitMap[suit]}.png`;      // npr. "10_spades.png"
 }
 
-function showAuctionButtons() {
-    const container = document.getElementById("auction-buttons");
-    container.innerHTML = "";
+// prikaziOpcije pravi dugmiće iz opcija koje šalje server; klijent ne zna
+// pravila, samo vraća jednu od ponuđenih vrednosti
+const naziviPonuda = { 0: "PAS", 8: "IGRA", 9: "BETL", 10: "SANS" };
+const poljaOdgovora = { prati: "prati", kontra_odgovor: "kontra", claim_odgovor: "prihvatam", revans: "prihvatam" };
 
-    const buttons = ["2", "3", "4", "5", "6", "7", "IGRA", "BETL", "SANS", "PAS"];
-
-    buttons.forEach(label => {
-        const btn = document.createElement("button");
-        btn.textContent = label;
-        btn.onclick = () => {
-            socket.send(JSON.stringify({
-                type: "licitacija",
-                value: label.toLowerCase()
-            }));
-            container.innerHTML = ""; // ukloni dugmad posle klika
-        };
-        container.appendChild(btn);
-    });
-}
\ No newline at end of file
+function dugme(natpis, poruka) {
+    const btn = document.createElement("button");
+    btn.textContent = natpis;
+    btn.onclick = () => socket.send(JSON.stringify(poruka));
+    return btn;
+}
+
+function prikaziOpcije(st) {
+    const container = document.getElementById("actions");
+    container.innerHTML = "";
+    const akcije = st.akcije || [];
+    if (st.cards) {
+        mycards = st.cards;
+    }
+    if (st.ponude) {
+        st.ponude.forEach(v => container.appendChild(dugme(naziviPonuda[v] || String(v), { type: "bid", value: v })));
+    }
+    if (akcije.includes("potvrdi_igru")) {
+        if (st.aduti.length === 0) {
+            container.appendChild(dugme("POTVRDI", { type: "potvrdi_igru" }));
+        }
+        st.aduti.forEach(a => container.appendChild(dugme(a, { type: "potvrdi_igru", value: a })));
+    }
+    if (akcije.includes("stil_odabran")) {
+        st.aduti.forEach(a => container.appendChild(dugme(a, { type: "stil_odabran", stil: a })));
+    }
+    if (akcije.includes("odbaci_karte")) {
+        // bira se tačno st.broj karata, pa se šalju zajedno
+        const skart = [];
+        st.cards.forEach(c => {
+            const btn = document.createElement("button");
+            btn.textContent = c;
+            btn.onclick = () => {
+                btn.disabled = true;
+                skart.push(c);
+                if (skart.length === st.broj) {
+                    socket.send(JSON.stringify({ type: "odbaci_karte", karte: skart }));
+                }
+            };
+            container.appendChild(btn);
+        });
+    }
+    for (const [akcija, polje] of Object.entries(poljaOdgovora)) {
+        if (akcije.includes(akcija)) {
+            st.odgovori.forEach(o => container.appendChild(dugme(o ? "DA" : "NE", { type: akcija, [polje]: o })));
+        }
+    }
+    if (st.legalne) {
+        st.legalne.forEach(c => container.appendChild(dugme(c, { type: "baci_kartu", card: c })));
+    }
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ==== Statički fajlovi ====
+// index.html, game.js, style.css i karte su ugrađeni u program, pa server radi iz
+// bilo kog direktorijuma. game.js i style.css se služe i pod imenom sa heš
+// sadržaja (game.1a2b3c4d5e.js) koje index.html koristi, pa se mogu keširati
+// zauvek. Za rad na frontendu static=<direktorijum> čita fajlove sa diska.
+//
+//go:embed static
+var ugradjeniFajlovi embed.FS
+
+const (
+	kesZauvek  = "public, max-age=31536000, immutable"
+	kesKarte   = "public, max-age=2592000" // 30 dana, karte se retko menjaju
+	kesProveri = "no-cache"                // uvek pitaj server, ETag štedi prenos
+)
+
+// sa heš imenom se služe samo fajlovi koje index.html učitava
+var hesiraniFajlovi = []string{"game.js", "style.css"}
+
+type staticFajl struct {
+	sadrzaj []byte
+	etag    string
+	kes     string
+}
+
+type staticki map[string]*staticFajl // putanja ("/cards/7h.png") -> fajl
+
+func noviStaticki(fsys fs.FS) (staticki, error) {
+	s := staticki{}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		kes := kesProveri
+		if strings.HasPrefix(p, "cards/") {
+			kes = kesKarte
+		}
+		s["/"+p] = &staticFajl{sadrzaj: data, etag: etag(data), kes: kes}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	index, ok := s["/index.html"]
+	if !ok {
+		return nil, fmt.Errorf("nema index.html")
+	}
+	html := index.sadrzaj
+	for _, ime := range hesiraniFajlovi {
+		f, ok := s["/"+ime]
+		if !ok {
+			return nil, fmt.Errorf("nema %s", ime)
+		}
+		ext := path.Ext(ime)
+		hesirano := strings.TrimSuffix(ime, ext) + "." + strings.Trim(f.etag, `"`)[:10] + ext
+		s["/"+hesirano] = &staticFajl{sadrzaj: f.sadrzaj, etag: f.etag, kes: kesZauvek}
+		// href="style.css", href="/style.css" i src="game.js" postaju heš imena
+		re := regexp.MustCompile(`((?:src|href)=")/?` + regexp.QuoteMeta(ime) + `"`)
+		html = re.ReplaceAll(html, []byte("${1}/"+hesirano+`"`))
+	}
+	s["/index.html"] = &staticFajl{sadrzaj: html, etag: etag(html), kes: kesProveri}
+	return s, nil
+}
+
+func etag(data []byte) string {
+	h := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(h[:8]) + `"`
+}
+
+func (s staticki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Path
+	if strings.HasSuffix(p, "/") {
+		p += "index.html"
+	}
+	f, ok := s[p]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("ETag", f.etag)
+	w.Header().Set("Cache-Control", f.kes)
+	// ServeContent sam odgovara sa 304 kad se If-None-Match poklopi sa ETag-om
+	http.ServeContent(w, r, p, time.Time{}, bytes.NewReader(f.sadrzaj))
+}
+
+// staticHandler služi ugrađene fajlove, ili fajlove sa diska kad je zadat static
+func staticHandler() (http.Handler, error) {
+	if konfig.Static != "" {
+		disk := http.FileServer(http.Dir(konfig.Static))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", kesProveri)
+			disk.ServeHTTP(w, r)
+		}), nil
+	}
+	sub, err := fs.Sub(ugradjeniFajlovi, "static")
+	if err != nil {
+		return nil, err
+	}
+	return noviStaticki(sub)
+}
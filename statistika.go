@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"multiplayer-game/preferans"
+)
+
+// ==== Zapisi odigranih ruku i mečeva ====
+// Svaka završena ruka i svaki završen meč dopisuju se kao jedan JSON red, a
+// statistika se uvek računa iz zapisa, pa se lako filtrira po vremenu.
+var (
+	rukePutanja   = "ruke.jsonl"
+	meceviPutanja = "mecevi.jsonl"
+	zapisi        *statsStore
+)
+
+type ZapisRuke struct {
+	Vreme      time.Time `json:"vreme"`
+	Pravila    string    `json:"pravila"`
+	Igraci     [3]int    `json:"igraci"` // ID naloga po mestu
+	Deklarant  int       `json:"deklarant"`
+	Ponuda     int       `json:"ponuda"`
+	Kontra     int       `json:"kontra"`
+	Kontrirali [3]bool   `json:"kontrirali"`
+	Pratili    [3]bool   `json:"pratili"`
+	Prosao     bool      `json:"prosao"`
+	Stihovi    [3]int    `json:"stihovi"`
+	Bule       [3]int    `json:"bule"`
+	// ruka završena prihvaćenim zahtevom deklaranta
+	Zahtev *ZapisZahteva `json:"zahtev,omitempty"`
+	// broj saveta po mestu; traže se samo u sobama za trening
+	Saveti [3]int `json:"saveti"`
+	// podela i tok ruke, da bi ruka mogla ponovo da se odigra (vidi vezbanje.go)
+	Podela     []string `json:"podela,omitempty"`     // po 10 karata za mesta 0–2, pa talon
+	Prvi       int      `json:"prvi"`                 // ko je prvi licitirao
+	Licitacija []Ponuda `json:"licitacija,omitempty"` // ponude redom
+	Adut       string   `json:"adut,omitempty"`
+	Skart      []string `json:"skart,omitempty"`
+	Bacene     []string `json:"bacene,omitempty"` // karte redom kojim su bačene
+}
+
+type ZapisMeca struct {
+	Vreme       time.Time `json:"vreme"`
+	Pravila     string    `json:"pravila"`
+	Igraci      [3]int    `json:"igraci"`
+	PocetnaBula int       `json:"pocetna_bula"`
+	Bule        [3]int    `json:"bule"`
+	Saldo       [3]int    `json:"saldo"`
+	Podela      int       `json:"podela"`
+}
+
+type statsStore struct {
+	mu     sync.Mutex
+	ruke   []ZapisRuke
+	mecevi []ZapisMeca
+}
+
+func loadStats() (*statsStore, error) {
+	s := &statsStore{}
+	if err := citajJSONL(rukePutanja, func(red []byte) error {
+		var z ZapisRuke
+		err := json.Unmarshal(red, &z)
+		s.ruke = append(s.ruke, z)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if err := citajJSONL(meceviPutanja, func(red []byte) error {
+		var z ZapisMeca
+		err := json.Unmarshal(red, &z)
+		s.mecevi = append(s.mecevi, z)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// citajJSONL poziva dekodiranje za svaki red fajla; fajl koji ne postoji je prazan
+func citajJSONL(path string, dekodiraj func([]byte) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for red := 1; sc.Scan(); red++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		if err := dekodiraj(sc.Bytes()); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, red, err)
+		}
+	}
+	return sc.Err()
+}
+
+func dopisi(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *statsStore) upisiRuku(z ZapisRuke) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ruke = append(s.ruke, z)
+	return dopisi(rukePutanja, z)
+}
+
+func (s *statsStore) upisiMec(z ZapisMeca) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mecevi = append(s.mecevi, z)
+	return dopisi(meceviPutanja, z)
+}
+
+// sporniZahtevi su ruke završene nepoštenim zahtevom, od najnovije
+func (s *statsStore) sporniZahtevi() []ZapisRuke {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []ZapisRuke{}
+	for i := len(s.ruke) - 1; i >= 0; i-- {
+		if z := s.ruke[i].Zahtev; z != nil && !z.Posten {
+			out = append(out, s.ruke[i])
+		}
+	}
+	return out
+}
+
+// ruka vraća zapis ruke po indeksu
+func (s *statsStore) ruka(i int) (ZapisRuke, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i < 0 || i >= len(s.ruke) {
+		return ZapisRuke{}, false
+	}
+	return s.ruke[i], true
+}
+
+// rukeZaVezbanje su poslednje ruke igrača sa sačuvanom podelom, od najnovije
+func (s *statsStore) rukeZaVezbanje(id int) []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []map[string]any{}
+	for i := len(s.ruke) - 1; i >= 0 && len(out) < 50; i-- {
+		z := s.ruke[i]
+		m := mesto(z.Igraci, id)
+		if m < 0 || z.Podela == nil {
+			continue
+		}
+		out = append(out, map[string]any{
+			"ruka":      i,
+			"vreme":     z.Vreme,
+			"pravila":   z.Pravila,
+			"mesto":     m,
+			"deklarant": z.Deklarant,
+			"ponuda":    z.Ponuda,
+			"prosao":    z.Prosao,
+			"stihovi":   z.Stihovi,
+		})
+	}
+	return out
+}
+
+// ==== Računanje statistike ====
+type Uspeh struct {
+	Ukupno   int     `json:"ukupno"`
+	Prosao   int     `json:"prosao"`
+	Procenat float64 `json:"procenat"`
+}
+
+func (u *Uspeh) dodaj(prosao bool) {
+	u.Ukupno++
+	if prosao {
+		u.Prosao++
+	}
+	u.Procenat = procenat(u.Prosao, u.Ukupno)
+}
+
+type StatistikaIgraca struct {
+	Igrac     int               `json:"igrac"`
+	Ime       string            `json:"ime"`
+	Rejting   float64           `json:"rejting"`
+	Ruku      int               `json:"ruku"`
+	Deklarant Uspeh             `json:"deklarant"`
+	PoUgovoru map[string]*Uspeh `json:"po_ugovoru"`
+	Betl      Uspeh             `json:"betl"`
+	Sans      Uspeh             `json:"sans"`
+	Kontra    struct {
+		Datih   int     `json:"datih"`
+		Tacnih  int     `json:"tacnih"`
+		Tacnost float64 `json:"tacnost"`
+	} `json:"kontra"`
+	Pracenje struct {
+		Prilika  int     `json:"prilika"`
+		Pratio   int     `json:"pratio"`
+		Procenat float64 `json:"procenat"`
+	} `json:"pracenje"`
+	Meceva      int     `json:"meceva"`
+	ProsekBula  float64 `json:"prosecna_promena_bula"`
+	UkupnoSaldo int     `json:"ukupno_saldo"`
+}
+
+func procenat(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return math.Round(1000*float64(a)/float64(b)) / 10
+}
+
+// mesto vraća mesto igrača u zapisu ili -1
+func mesto(igraci [3]int, id int) int {
+	for i, x := range igraci {
+		if x == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// statistika računa statistiku igrača iz zapisa u intervalu [od, do); nulto vreme znači bez granice
+func (s *statsStore) statistika(id int, od, do time.Time) StatistikaIgraca {
+	st := StatistikaIgraca{Igrac: id, PoUgovoru: map[string]*Uspeh{}}
+	if u := korisnici.get(id); u != nil {
+		st.Ime = u.Ime
+	}
+	st.Rejting = rejtinzi.vrednost(id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, z := range s.ruke {
+		i := mesto(z.Igraci, id)
+		if i < 0 || !uIntervalu(z.Vreme, od, do) {
+			continue
+		}
+		st.Ruku++
+		if i == z.Deklarant {
+			st.Deklarant.dodaj(z.Prosao)
+			ugovor := preferans.NazivPonude(z.Ponuda)
+			if st.PoUgovoru[ugovor] == nil {
+				st.PoUgovoru[ugovor] = &Uspeh{}
+			}
+			st.PoUgovoru[ugovor].dodaj(z.Prosao)
+			switch z.Ponuda {
+			case preferans.Betl:
+				st.Betl.dodaj(z.Prosao)
+			case preferans.Sans:
+				st.Sans.dodaj(z.Prosao)
+			}
+		} else {
+			st.Pracenje.Prilika++
+			if z.Pratili[i] {
+				st.Pracenje.Pratio++
+			}
+		}
+		// kontra je tačna ako se ispuni ono na šta je igrač kladio: branilac na pad, deklarant na prolaz
+		if z.Kontrirali[i] {
+			st.Kontra.Datih++
+			if (i == z.Deklarant) == z.Prosao {
+				st.Kontra.Tacnih++
+			}
+		}
+	}
+	st.Kontra.Tacnost = procenat(st.Kontra.Tacnih, st.Kontra.Datih)
+	st.Pracenje.Procenat = procenat(st.Pracenje.Pratio, st.Pracenje.Prilika)
+
+	promena := 0
+	for _, z := range s.mecevi {
+		i := mesto(z.Igraci, id)
+		if i < 0 || !uIntervalu(z.Vreme, od, do) {
+			continue
+		}
+		st.Meceva++
+		promena += z.Bule[i] - z.PocetnaBula
+		st.UkupnoSaldo += z.Saldo[i]
+	}
+	if st.Meceva > 0 {
+		st.ProsekBula = math.Round(10*float64(promena)/float64(st.Meceva)) / 10
+	}
+	return st
+}
+
+// igraci vraća sve naloge koji se pojavljuju u zapisima
+func (s *statsStore) igraci() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vidjeni := map[int]bool{}
+	for _, z := range s.ruke {
+		for _, id := range z.Igraci {
+			vidjeni[id] = true
+		}
+	}
+	delete(vidjeni, 0)
+	ids := make([]int, 0, len(vidjeni))
+	for id := range vidjeni {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func uIntervalu(t, od, do time.Time) bool {
+	return (od.IsZero() || !t.Before(od)) && (do.IsZero() || t.Before(do))
+}
+
+// ==== HTTP ====
+// parsirajVreme prihvata datum (2006-01-02) ili RFC3339
+func parsirajVreme(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func interval(r *http.Request) (od, do time.Time, err error) {
+	if od, err = parsirajVreme(r.URL.Query().Get("od")); err != nil {
+		return od, do, tr("los_parametar", "od")
+	}
+	if do, err = parsirajVreme(r.URL.Query().Get("do")); err != nil {
+		return od, do, tr("los_parametar", "do")
+	}
+	return od, do, nil
+}
+
+// GET /api/players/{id}/stats?od=&do=
+func handleStats(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || korisnici.get(id) == nil {
+		jsonError(w, r, http.StatusNotFound, tr("nepoznat_igrac"))
+		return
+	}
+	od, do, err := interval(r)
+	if err != nil {
+		jsonError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, zapisi.statistika(id, od, do))
+}
+
+// poredjenja za tabelu; veće je bolje osim za bule
+var poredjenja = map[string]func(a, b StatistikaIgraca) bool{
+	"rejting": func(a, b StatistikaIgraca) bool { return a.Rejting > b.Rejting },
+	"uspeh":   func(a, b StatistikaIgraca) bool { return a.Deklarant.Procenat > b.Deklarant.Procenat },
+	"kontra":  func(a, b StatistikaIgraca) bool { return a.Kontra.Tacnost > b.Kontra.Tacnost },
+	"bule":    func(a, b StatistikaIgraca) bool { return a.ProsekBula < b.ProsekBula },
+	"saldo":   func(a, b StatistikaIgraca) bool { return a.UkupnoSaldo > b.UkupnoSaldo },
+	"ruke":    func(a, b StatistikaIgraca) bool { return a.Ruku > b.Ruku },
+}
+
+// GET /api/leaderboard?po=rejting&od=&do=&min=10&limit=20
+func handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	po := q.Get("po")
+	if po == "" {
+		po = "rejting"
+	}
+	manje, ok := poredjenja[po]
+	if !ok {
+		jsonError(w, r, http.StatusBadRequest, tr("nepoznato_poredjenje", po))
+		return
+	}
+	od, do, err := interval(r)
+	if err != nil {
+		jsonError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	minRuku, limit := 1, 20
+	if v := q.Get("min"); v != "" {
+		if minRuku, err = strconv.Atoi(v); err != nil {
+			jsonError(w, r, http.StatusBadRequest, tr("los_parametar", "min"))
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			jsonError(w, r, http.StatusBadRequest, tr("los_parametar", "limit"))
+			return
+		}
+	}
+
+	tabela := []StatistikaIgraca{}
+	for _, id := range zapisi.igraci() {
+		st := zapisi.statistika(id, od, do)
+		if st.Ruku >= minRuku {
+			tabela = append(tabela, st)
+		}
+	}
+	sort.SliceStable(tabela, func(i, j int) bool { return manje(tabela[i], tabela[j]) })
+	if len(tabela) > limit {
+		tabela = tabela[:limit]
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"po": po, "igraci": tabela})
+}
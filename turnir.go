@@ -0,0 +1,716 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"multiplayer-game/preferans"
+)
+
+// ==== Turniri ====
+// Igrači se raspoređuju za stolove od po tri, svako kolo ima isti broj podela i
+// svi stolovi u kolu dobijaju iste karte (seme turnira + kolo + podela). Igrač se
+// poredi sa svima koji su na istoj podeli sedeli na istom mestu, kao u duplikat bridžu.
+const (
+	turnirPrijave = "prijave"
+	turnirUToku   = "u_toku"
+	turnirGotov   = "zavrsen"
+)
+
+var (
+	turniriPutanja = "turniri.json"
+	turniri        *tournamentStore
+
+	errNemaTurnira  = tr("nema_turnira")
+	errNijePrijava  = tr("prijave_zatvorene")
+	errBrojIgraca   = tr("broj_igraca_turnira")
+	errNijeOrganiz  = tr("samo_organizator")
+	errNisiNaTurnir = tr("nisi_na_turniru")
+)
+
+// RezultatPodele su poeni jednog stola na jednoj podeli, po mestima.
+// Poeni su u supama: bula vredi deset supa, supe upisane drugima se dobijaju, a upisane na sebe gube.
+type RezultatPodele struct {
+	Kolo   int    `json:"kolo"`
+	Sto    int    `json:"sto"`
+	Podela int    `json:"podela"`
+	Igraci [3]int `json:"igraci"`
+	Poeni  [3]int `json:"poeni"`
+}
+
+type Tournament struct {
+	mu           sync.Mutex
+	ID           string           `json:"id"`
+	Naziv        string           `json:"naziv"`
+	Pravila      string           `json:"pravila"`
+	Organizator  int              `json:"organizator"`
+	Kola         int              `json:"kola"`
+	PodelaPoKolu int              `json:"podela_po_kolu"`
+	Seme         int64            `json:"seme"`
+	Igraci       []int            `json:"igraci"`
+	Stanje       string           `json:"stanje"`
+	Kolo         int              `json:"kolo"`     // trenutno kolo, od 1
+	Raspored     [][][3]int       `json:"raspored"` // raspored[kolo-1][sto] su igrači po mestima
+	Zavrseni     map[int]bool     `json:"zavrseni"` // stolovi završeni u trenutnom kolu
+	Rezultati    []RezultatPodele `json:"rezultati"`
+	Napravljen   time.Time        `json:"napravljen"`
+
+	povezani map[int]*Player // igrači koji su ušli preko /ws?turnir=
+}
+
+// turnirskiSto je deo sobe koja pripada turniru
+type turnirskiSto struct {
+	t      *Tournament
+	kolo   int
+	sto    int
+	podela int // broj podeljenih ruku u ovom kolu
+}
+
+type tournamentStore struct {
+	mu      sync.Mutex
+	path    string
+	turniri map[string]*Tournament
+	sledeci int
+}
+
+func loadTournaments(path string) (*tournamentStore, error) {
+	s := &tournamentStore{path: path, turniri: map[string]*Tournament{}, sledeci: 1}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var lista []*Tournament
+	if err := json.Unmarshal(data, &lista); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, t := range lista {
+		t.povezani = map[int]*Player{}
+		// stolovi koji nisu završili kolo pre restarta igraju ga ponovo
+		rezultati := t.Rezultati[:0]
+		for _, rz := range t.Rezultati {
+			if rz.Kolo < t.Kolo || t.Zavrseni[rz.Sto] {
+				rezultati = append(rezultati, rz)
+			}
+		}
+		t.Rezultati = rezultati
+		s.turniri[t.ID] = t
+		s.sledeci++
+	}
+	return s, nil
+}
+
+func (s *tournamentStore) save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lista := make([]*Tournament, 0, len(s.turniri))
+	for _, t := range s.turniri {
+		t.mu.Lock()
+		lista = append(lista, t)
+	}
+	sort.Slice(lista, func(i, j int) bool { return lista[i].Napravljen.Before(lista[j].Napravljen) })
+	data, err := json.MarshalIndent(lista, "", "  ")
+	for _, t := range lista {
+		t.mu.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *tournamentStore) get(id string) *Tournament {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.turniri[id]
+}
+
+func (s *tournamentStore) napravi(t *Tournament) {
+	s.mu.Lock()
+	t.ID = fmt.Sprintf("t%d", s.sledeci)
+	s.sledeci++
+	s.turniri[t.ID] = t
+	s.mu.Unlock()
+}
+
+// rasporedKola raspoređuje igrače za stolove. Igrači su u tabeli sa tri kolone
+// i po jednim redom za svaki sto; u kolu k druga kolona se pomera za k redova, a
+// treća za 2k, pa se dva igrača sreću najviše jednom dok je kola manje od
+// maxKola. Kolone se u svakom kolu pomeraju i po mestima, da bi se igrač poredio
+// sa različitim igračima i da ne bi stalno igrao prvi ili poslednji.
+func rasporedKola(igraci []int, kolo int) [][3]int {
+	redova := len(igraci) / 3
+	stolovi := make([][3]int, redova)
+	for sto := range stolovi {
+		for kolona := 0; kolona < 3; kolona++ {
+			red := (sto + kolona*kolo) % redova
+			stolovi[sto][(kolona+kolo)%3] = igraci[kolona*redova+red]
+		}
+	}
+	return stolovi
+}
+
+// maxKola je najviše kola bez ponovljenog susreta: pomeraji k i 2k moraju biti
+// različiti po modulu broja stolova. Za jedan sto protivnici su uvek isti, pa
+// kola služe samo da se promene mesta.
+func maxKola(igraca int) int {
+	redova := igraca / 3
+	switch {
+	case redova == 1:
+		return 3
+	case redova%2 == 0:
+		return redova / 2
+	}
+	return redova
+}
+
+// spil vraća karte za podelu; isto seme daje iste karte na svim stolovima kola
+func (ts *turnirskiSto) spil() []string {
+	seme := ts.t.Seme + int64(ts.kolo)*1000 + int64(ts.podela)
+	return preferans.ShuffleCards(preferans.Deck, rand.New(rand.NewSource(seme)))
+}
+
+// pocni pokreće turnir: pravi raspored i sobe za prvo kolo
+func (t *Tournament) pocni() error {
+	t.mu.Lock()
+	if t.Stanje != turnirPrijave {
+		t.mu.Unlock()
+		return errNijePrijava
+	}
+	if len(t.Igraci) == 0 || len(t.Igraci)%3 != 0 {
+		t.mu.Unlock()
+		return errBrojIgraca
+	}
+	if n := maxKola(len(t.Igraci)); t.Kola > n {
+		t.mu.Unlock()
+		return tr("previse_kola", len(t.Igraci), n)
+	}
+	// redosled prijava ne sme da određuje protivnike
+	rand.New(rand.NewSource(t.Seme)).Shuffle(len(t.Igraci), func(i, j int) {
+		t.Igraci[i], t.Igraci[j] = t.Igraci[j], t.Igraci[i]
+	})
+	t.Raspored = nil
+	for k := 0; k < t.Kola; k++ {
+		t.Raspored = append(t.Raspored, rasporedKola(t.Igraci, k))
+	}
+	t.Stanje = turnirUToku
+	t.mu.Unlock()
+	t.novoKolo()
+	return nil
+}
+
+func (t *Tournament) sobaID(kolo, sto int) string {
+	return fmt.Sprintf("%s-k%d-s%d", t.ID, kolo, sto+1)
+}
+
+// novoKolo prelazi u sledeće kolo
+func (t *Tournament) novoKolo() {
+	t.mu.Lock()
+	t.Kolo++
+	t.Zavrseni = map[int]bool{}
+	t.mu.Unlock()
+	if err := turniri.save(); err != nil {
+		slog.Error("čuvanje turnira", "turnir", t.ID, "greska", err)
+	}
+	t.napraviSobe()
+}
+
+// napraviSobe pravi sobe za stolove trenutnog kola koji još nisu završili i
+// premešta u njih igrače koji su već povezani
+func (t *Tournament) napraviSobe() {
+	t.mu.Lock()
+	kolo := t.Kolo
+	raspored := t.Raspored[kolo-1]
+	zavrseni := t.Zavrseni
+	povezani := make(map[int]*Player, len(t.povezani))
+	for id, p := range t.povezani {
+		povezani[id] = p
+	}
+	t.mu.Unlock()
+
+	rules, err := preferans.RulesZa(t.Pravila)
+	if err != nil {
+		slog.Error("pravila turnira", "turnir", t.ID, "greska", err)
+		return
+	}
+	pune := []*Room{}
+	mu.Lock()
+	for sto, mesta := range raspored {
+		if zavrseni[sto] {
+			continue
+		}
+		id := t.sobaID(kolo, sto)
+		room := &Room{id: id, rules: rules, maxRefe: rules.MaxRefe, turnir: &turnirskiSto{t: t, kolo: kolo, sto: sto}}
+		rooms[id] = room
+		room.mu.Lock()
+		for mesto, korisnik := range mesta {
+			if p, ok := povezani[korisnik]; ok {
+				p.id = mesto
+				p.uSobu(id)
+				room.players = append(room.players, p)
+				room.posalji(p, map[string]any{
+					"type":    "turnir_kolo",
+					"message": tr("turnir_kolo", kolo, sto+1),
+					"turnir":  t.ID,
+					"kolo":    kolo,
+					"sto":     sto + 1,
+					"id":      p.id,
+				})
+			}
+		}
+		room.mu.Unlock()
+		if len(room.players) == 3 {
+			pune = append(pune, room)
+		}
+	}
+	mu.Unlock()
+
+	for _, room := range pune {
+		pocniTurnirskiSto(room)
+	}
+}
+
+func pocniTurnirskiSto(room *Room) {
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	sort.Slice(room.players, func(i, j int) bool { return room.players[i].id < room.players[j].id })
+	room.broadcast(map[string]any{
+		"type":    "igraci",
+		"message": tr("svi_za_stolom"),
+		"imena":   room.imena(),
+		"rejting": room.rejtingStola(),
+	})
+	room.match = newMatch(room)
+	dealCards(room)
+	room.javiStanje()
+}
+
+// sedi postavlja igrača za njegov sto u trenutnom kolu
+func (t *Tournament) sedi(p *Player) error {
+	t.mu.Lock()
+	if t.Stanje != turnirUToku {
+		t.mu.Unlock()
+		return tr("turnir_nije_u_toku")
+	}
+	kolo := t.Kolo
+	sto, mesto := -1, -1
+	for s, mesta := range t.Raspored[kolo-1] {
+		for m, korisnik := range mesta {
+			if korisnik == p.user.ID {
+				sto, mesto = s, m
+			}
+		}
+	}
+	if sto < 0 {
+		t.mu.Unlock()
+		return errNisiNaTurnir
+	}
+	t.povezani[p.user.ID] = p
+	zavrsen := t.Zavrseni[sto]
+	t.mu.Unlock()
+
+	rules, _ := preferans.RulesZa(t.Pravila)
+	p.id = mesto
+	p.uSobu(t.sobaID(kolo, sto))
+	ja := map[string]any{
+		"type":    "you_are",
+		"id":      p.id,
+		"ime":     p.ime(),
+		"pravila": rules,
+		"turnir":  t.ID,
+		"kolo":    kolo,
+		"sto":     sto + 1,
+	}
+	if zavrsen {
+		// sto je obrisan, pa igrač do sledećeg kola nema sobu kroz koju bi dobijao poruke
+		p.conn.WriteJSON(ja)
+		p.conn.WriteJSON(map[string]any{
+			"type":    "info",
+			"message": tr("sto_zavrsio_cekaj"),
+		})
+		return nil
+	}
+
+	mu.Lock()
+	room := rooms[p.room]
+	if room == nil {
+		mu.Unlock()
+		return tr("sto_zavrsio")
+	}
+	room.mu.Lock()
+	room.posalji(p, ja)
+	// ponovno povezivanje zamenjuje staru vezu na istom mestu
+	zamenjen := false
+	for i, pl := range room.players {
+		if pl.user.ID == p.user.ID {
+			room.players[i] = p
+			zamenjen = true
+		}
+	}
+	if !zamenjen {
+		room.players = append(room.players, p)
+	}
+	puna := len(room.players) == 3 && room.match == nil
+	if !puna {
+		room.posaljiStanje(p)
+	}
+	room.mu.Unlock()
+	mu.Unlock()
+	if puna {
+		pocniTurnirskiSto(room)
+	}
+	return nil
+}
+
+// zapocniPodelu zove dealCards pre deljenja; vraća false kad je sto odigrao sve podele kola
+func (ts *turnirskiSto) zapocniPodelu(r *Room) ([]string, bool) {
+	t := ts.t
+	if ts.podela >= t.PodelaPoKolu {
+		return nil, false
+	}
+	karte := ts.spil()
+	ts.podela++
+	// podela koja se ne odigra (svi pas) vredi nula poena
+	t.mu.Lock()
+	var igraci [3]int
+	for _, p := range r.players {
+		igraci[p.id] = p.user.ID
+	}
+	t.Rezultati = append(t.Rezultati, RezultatPodele{Kolo: ts.kolo, Sto: ts.sto, Podela: ts.podela, Igraci: igraci})
+	t.mu.Unlock()
+	return karte, true
+}
+
+// ponoviPodelu vraća brojač podele i briše njen rezultat, pa sledeće deljenje daje iste karte
+func (ts *turnirskiSto) ponoviPodelu() {
+	if ts.podela == 0 {
+		return
+	}
+	t := ts.t
+	t.mu.Lock()
+	for i := len(t.Rezultati) - 1; i >= 0; i-- {
+		o := t.Rezultati[i]
+		if o.Kolo == ts.kolo && o.Sto == ts.sto && o.Podela == ts.podela {
+			t.Rezultati = slices.Delete(t.Rezultati, i, i+1)
+			break
+		}
+	}
+	t.mu.Unlock()
+	ts.podela--
+}
+
+// upisi beleži ishod odigrane podele
+func (ts *turnirskiSto) upisi(o preferans.Obracun) {
+	var poeni [3]int
+	supe := 0
+	for i := range poeni {
+		poeni[i] = -10*o.Bule[i] + o.Supe[i]
+		supe += o.Supe[i]
+	}
+	poeni[o.Deklarant] -= supe
+
+	ts.t.mu.Lock()
+	defer ts.t.mu.Unlock()
+	for i := len(ts.t.Rezultati) - 1; i >= 0; i-- {
+		rz := &ts.t.Rezultati[i]
+		if rz.Kolo == ts.kolo && rz.Sto == ts.sto && rz.Podela == ts.podela {
+			rz.Poeni = poeni
+			return
+		}
+	}
+}
+
+// zavrsiSto se zove iz dealCards kad sto odigra sve podele kola. Soba je tada
+// zaključana, pa se ostatak (brisanje sobe, novo kolo) radi u posebnoj gorutini.
+func (ts *turnirskiSto) zavrsiSto(r *Room) {
+	t := ts.t
+	t.mu.Lock()
+	t.Zavrseni[ts.sto] = true
+	svi := len(t.Zavrseni) == len(t.Raspored[t.Kolo-1])
+	poslednje := t.Kolo == t.Kola
+	if svi && poslednje {
+		t.Stanje = turnirGotov
+	}
+	t.mu.Unlock()
+
+	r.broadcast(map[string]any{
+		"type":    "turnir_sto_gotov",
+		"message": tr("sto_zavrsio_kolo"),
+		"plasman": t.plasman(),
+	})
+	go ts.posleStola(svi, poslednje)
+}
+
+func (ts *turnirskiSto) posleStola(svi, poslednje bool) {
+	t := ts.t
+	mu.Lock()
+	delete(rooms, t.sobaID(ts.kolo, ts.sto))
+	mu.Unlock()
+
+	switch {
+	case svi && poslednje:
+		t.objavi(map[string]any{
+			"type":    "turnir_kraj",
+			"message": tr("turnir_zavrsen", t.Naziv),
+			"plasman": t.plasman(),
+		})
+		turniri.save()
+	case svi:
+		t.novoKolo()
+	default:
+		turniri.save()
+	}
+}
+
+// objavi šalje poruku svim povezanim igračima turnira; zove se kad su svi stolovi
+// kola obrisani, pa ide direktno na veze
+func (t *Tournament) objavi(msg map[string]any) {
+	t.mu.Lock()
+	povezani := make([]*Player, 0, len(t.povezani))
+	for _, p := range t.povezani {
+		povezani = append(povezani, p)
+	}
+	t.mu.Unlock()
+	for _, p := range povezani {
+		p.conn.WriteJSON(msg)
+	}
+}
+
+// ==== Plasman ====
+type Plasman struct {
+	Igrac      int     `json:"igrac"`
+	Ime        string  `json:"ime"`
+	Procenat   float64 `json:"procenat"`
+	Bodovi     float64 `json:"bodovi"`
+	Poredjenja int     `json:"poredjenja"`
+	Poeni      int     `json:"poeni"`
+}
+
+// plasman boduje svaki rezultat prema svim stolovima koji su igrali istu podelu na
+// istom mestu: bod za bolji rezultat, pola boda za isti
+func (t *Tournament) plasman() []Plasman {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	type kljuc struct{ kolo, podela, mesto int }
+	grupe := map[kljuc][]int{} // poeni svih stolova
+	for _, rz := range t.Rezultati {
+		for m := 0; m < 3; m++ {
+			k := kljuc{rz.Kolo, rz.Podela, m}
+			grupe[k] = append(grupe[k], rz.Poeni[m])
+		}
+	}
+	po := map[int]*Plasman{}
+	for _, id := range t.Igraci {
+		po[id] = &Plasman{Igrac: id}
+		if u := korisnici.get(id); u != nil {
+			po[id].Ime = u.Ime
+		}
+	}
+	for _, rz := range t.Rezultati {
+		for m, id := range rz.Igraci {
+			pl, ok := po[id]
+			if !ok {
+				continue
+			}
+			pl.Poeni += rz.Poeni[m]
+			for _, drugi := range grupe[kljuc{rz.Kolo, rz.Podela, m}] {
+				pl.Poredjenja++
+				switch {
+				case rz.Poeni[m] > drugi:
+					pl.Bodovi++
+				case rz.Poeni[m] == drugi:
+					pl.Bodovi += 0.5
+				}
+			}
+			// poređenje sa samim sobom se ne računa
+			pl.Poredjenja--
+			pl.Bodovi -= 0.5
+		}
+	}
+	out := make([]Plasman, 0, len(po))
+	for _, pl := range po {
+		if pl.Poredjenja > 0 {
+			pl.Procenat = math.Round(1000*pl.Bodovi/float64(pl.Poredjenja)) / 10
+		}
+		out = append(out, *pl)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Procenat != out[j].Procenat {
+			return out[i].Procenat > out[j].Procenat
+		}
+		return out[i].Poeni > out[j].Poeni
+	})
+	return out
+}
+
+// ==== HTTP ====
+type noviTurnir struct {
+	Naziv        string `json:"naziv"`
+	Pravila      string `json:"pravila"`
+	Kola         int    `json:"kola"`
+	PodelaPoKolu int    `json:"podela_po_kolu"`
+	Seme         int64  `json:"seme"`
+}
+
+// POST /api/tournaments
+func handleTournamentCreate(w http.ResponseWriter, r *http.Request) {
+	u, err := userFromRequest(r)
+	if err != nil {
+		jsonError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	var n noviTurnir
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&n); err != nil {
+		jsonError(w, r, http.StatusBadRequest, tr("neispravan_zahtev"))
+		return
+	}
+	if n.Pravila == "" {
+		n.Pravila = konfig.Pravila
+	}
+	if _, err := preferans.RulesZa(n.Pravila); err != nil {
+		jsonError(w, r, http.StatusBadRequest, nepoznataPravila(n.Pravila))
+		return
+	}
+	if n.Kola < 1 || n.Kola > 20 || n.PodelaPoKolu < 1 || n.PodelaPoKolu > 100 {
+		jsonError(w, r, http.StatusBadRequest, tr("turnir_granice"))
+		return
+	}
+	if n.Seme == 0 {
+		n.Seme = time.Now().UnixNano()
+	}
+	t := &Tournament{
+		Naziv:        n.Naziv,
+		Pravila:      n.Pravila,
+		Organizator:  u.ID,
+		Kola:         n.Kola,
+		PodelaPoKolu: n.PodelaPoKolu,
+		Seme:         n.Seme,
+		Igraci:       []int{},
+		Stanje:       turnirPrijave,
+		Napravljen:   time.Now(),
+		povezani:     map[int]*Player{},
+	}
+	turniri.napravi(t)
+	if err := turniri.save(); err != nil {
+		slog.Error("čuvanje turnira", "turnir", t.ID, "greska", err)
+	}
+	writeJSON(w, http.StatusCreated, t.pregled())
+}
+
+// GET /api/tournaments
+func handleTournamentList(w http.ResponseWriter, r *http.Request) {
+	turniri.mu.Lock()
+	lista := make([]*Tournament, 0, len(turniri.turniri))
+	for _, t := range turniri.turniri {
+		lista = append(lista, t)
+	}
+	turniri.mu.Unlock()
+	sort.Slice(lista, func(i, j int) bool { return lista[i].Napravljen.Before(lista[j].Napravljen) })
+	out := []map[string]any{}
+	for _, t := range lista {
+		out = append(out, t.pregled())
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// GET /api/tournaments/{id}
+func handleTournament(w http.ResponseWriter, r *http.Request) {
+	t := turniri.get(r.PathValue("id"))
+	if t == nil {
+		jsonError(w, r, http.StatusNotFound, errNemaTurnira)
+		return
+	}
+	odgovor := t.pregled()
+	odgovor["plasman"] = t.plasman()
+	writeJSON(w, http.StatusOK, odgovor)
+}
+
+// POST /api/tournaments/{id}/register
+func handleTournamentRegister(w http.ResponseWriter, r *http.Request) {
+	u, err := userFromRequest(r)
+	if err != nil {
+		jsonError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	t := turniri.get(r.PathValue("id"))
+	if t == nil {
+		jsonError(w, r, http.StatusNotFound, errNemaTurnira)
+		return
+	}
+	t.mu.Lock()
+	if t.Stanje != turnirPrijave {
+		t.mu.Unlock()
+		jsonError(w, r, http.StatusConflict, errNijePrijava)
+		return
+	}
+	prijavljen := false
+	for _, id := range t.Igraci {
+		prijavljen = prijavljen || id == u.ID
+	}
+	if !prijavljen {
+		t.Igraci = append(t.Igraci, u.ID)
+	}
+	t.mu.Unlock()
+	if err := turniri.save(); err != nil {
+		slog.Error("čuvanje turnira", "turnir", t.ID, "greska", err)
+	}
+	writeJSON(w, http.StatusOK, t.pregled())
+}
+
+// POST /api/tournaments/{id}/start
+func handleTournamentStart(w http.ResponseWriter, r *http.Request) {
+	u, err := userFromRequest(r)
+	if err != nil {
+		jsonError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	t := turniri.get(r.PathValue("id"))
+	if t == nil {
+		jsonError(w, r, http.StatusNotFound, errNemaTurnira)
+		return
+	}
+	if t.Organizator != u.ID {
+		jsonError(w, r, http.StatusForbidden, errNijeOrganiz)
+		return
+	}
+	if err := t.pocni(); err != nil {
+		jsonError(w, r, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, t.pregled())
+}
+
+// pregled je javni opis turnira bez rezultata pojedinačnih podela
+func (t *Tournament) pregled() map[string]any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return map[string]any{
+		"id":             t.ID,
+		"naziv":          t.Naziv,
+		"pravila":        t.Pravila,
+		"organizator":    t.Organizator,
+		"kola":           t.Kola,
+		"podela_po_kolu": t.PodelaPoKolu,
+		"igraci":         t.Igraci,
+		"stanje":         t.Stanje,
+		"kolo":           t.Kolo,
+		"raspored":       t.Raspored,
+	}
+}
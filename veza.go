@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ==== Veza ====
+// veza šalje poruke kroz red koji prazni jedna gorutina, jer websocket ne dozvoljava
+// istovremeno pisanje iz više gorutina (soba, admin, chat). Ako klijent ne čita
+// i red se napuni, veza se zatvara umesto da zadrži celu sobu.
+const velicinaReda = 256
+
+type veza struct {
+	conn      *websocket.Conn
+	red       chan []byte
+	zatvori   sync.Once
+	zatvorena chan struct{}
+	pracen    atomic.Pointer[Player] // postavljen samo za sobu pod protokolskim praćenjem
+	poslednja atomic.Pointer[[]byte] // close okvir koji se šalje pre zatvaranja
+	jezik     atomic.Pointer[string] // jezik poruka; nil je konfig.Jezik (vidi prevodi.go)
+}
+
+// sveVeze su sve otvorene veze, za dubinu redova u metrikama i gašenje
+var (
+	sveVeze sync.Map // *veza -> struct{}
+	pisaci  sync.WaitGroup
+)
+
+func novaVeza(conn *websocket.Conn) *veza {
+	v := &veza{conn: conn, red: make(chan []byte, velicinaReda), zatvorena: make(chan struct{})}
+	sveVeze.Store(v, struct{}{})
+	metrika.veze.Add(1)
+	pisaci.Add(1)
+	go v.pisi()
+	return v
+}
+
+// pisi je jedina gorutina koja piše u websocket i jedina koja ga zatvara
+func (v *veza) pisi() {
+	defer pisaci.Done()
+	defer v.conn.Close()
+	for {
+		select {
+		case data := <-v.red:
+			v.conn.SetWriteDeadline(time.Now().Add(konfig.RokPisanja))
+			if err := v.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				metrika.greske.dodaj("write")
+				v.Close()
+				return
+			}
+		case <-v.zatvorena:
+			// pošalji ono što je već u redu (npr. poruku pre izbacivanja) pa zatvori
+			for len(v.red) > 0 {
+				v.conn.SetWriteDeadline(time.Now().Add(time.Second))
+				if v.conn.WriteMessage(websocket.TextMessage, <-v.red) != nil {
+					return
+				}
+			}
+			if okvir := v.poslednja.Load(); okvir != nil {
+				v.conn.WriteControl(websocket.CloseMessage, *okvir, time.Now().Add(time.Second))
+			}
+			return
+		}
+	}
+}
+
+// WriteJSON stavlja poruku u red; ima isti potpis kao websocket.Conn da bi pozivi ostali isti.
+// nil veza je igrač koji nije povezan (npr. sto vraćen posle restarta), poruka se odbacuje.
+func (v *veza) WriteJSON(msg any) error {
+	if v == nil {
+		return websocket.ErrCloseSent
+	}
+	tip := ""
+	if m, ok := msg.(map[string]any); ok {
+		tip, _ = m["type"].(string)
+		metrika.izlazne.dodaj(tip)
+		msg = prevedi(m, v.jezikPoruka())
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if p := v.pracen.Load(); p != nil {
+		protokol(p.log(), "izlaz", tip, data)
+	}
+	select {
+	case <-v.zatvorena:
+		return websocket.ErrCloseSent
+	default:
+	}
+	select {
+	case v.red <- data:
+		return nil
+	default:
+		metrika.greske.dodaj("write_queue_full")
+		v.Close()
+		return websocket.ErrCloseSent
+	}
+}
+
+// jezikPoruka je jezik na kome veza dobija tekst poruka
+func (v *veza) jezikPoruka() string {
+	if j := v.jezik.Load(); j != nil {
+		return *j
+	}
+	return konfig.Jezik
+}
+
+// prati uključuje ili isključuje beleženje poruka ove veze
+func (v *veza) prati(p *Player) {
+	v.pracen.Store(p)
+}
+
+func (v *veza) ReadMessage() (int, []byte, error) {
+	return v.conn.ReadMessage()
+}
+
+// zatvoriSa zatvara vezu websocket close okvirom sa datim kodom, posle poruka iz reda
+func (v *veza) zatvoriSa(kod int, razlog string) {
+	if v == nil {
+		return
+	}
+	okvir := websocket.FormatCloseMessage(kod, razlog)
+	v.poslednja.Store(&okvir)
+	v.Close()
+}
+
+func (v *veza) Close() error {
+	if v == nil {
+		return nil
+	}
+	v.zatvori.Do(func() {
+		close(v.zatvorena)
+		sveVeze.Delete(v)
+		metrika.veze.Add(-1)
+	})
+	return nil
+}
+
+// dubinaRedova vraća zbir i najveću dubinu redova za slanje
+func dubinaRedova() (zbir, najveca int) {
+	sveVeze.Range(func(k, _ any) bool {
+		n := len(k.(*veza).red)
+		zbir += n
+		if n > najveca {
+			najveca = n
+		}
+		return true
+	})
+	return zbir, najveca
+}
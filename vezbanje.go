@@ -0,0 +1,425 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"multiplayer-game/preferans"
+)
+
+// ==== Vežbanje ====
+// Igrač učita ruku (svoju sačuvanu ili nalepljen zapis), sedne na bilo koje
+// mesto i igra je ponovo protiv botova, od licitacije ili od zadatog štiha sa
+// originalnim ugovorom. Botovi odlučuju po savetima iz preferans/savet.go, pod
+// istom bravom sobe kao i poruke igrača. Vežba je jedna podela: na kraju se
+// ishod poredi sa originalom i sa najboljom igrom kad su sve karte otvorene.
+// Ništa se ne upisuje u statistiku, a soba nestaje kad igrač ode.
+
+// maxPotezaBotova čuva od beskonačne petlje ako bot pošalje potez koji ne prolazi
+const maxPotezaBotova = 200
+
+type vezbanje struct {
+	korisnik  int
+	zapis     ZapisRuke
+	mesto     int // mesto igrača, za ostala dva igraju botovi
+	odStiha   int // 0 je od licitacije
+	podeljeno bool
+	pocelo    bool
+	gotovo    bool
+	// odigravanje kad je igra prešla na igrača, za poređenje sa najboljom igrom
+	pocetak   *preferans.Odigravanje
+	deklarant int
+	najvise   bool
+}
+
+var brojVezbi int // čuva ga mu
+
+type zahtevVezbe struct {
+	Ruka    *int       `json:"ruka"`     // indeks iz GET /api/vezbanje
+	Zapis   *ZapisRuke `json:"zapis"`    // ili nalepljen zapis ruke
+	Mesto   int        `json:"mesto"`    // 0–2
+	OdStiha int        `json:"od_stiha"` // 0 od licitacije, 1–10 od tog štiha
+}
+
+// GET /api/vezbanje — sačuvane ruke korisnika koje mogu ponovo da se odigraju
+func handleVezbanjeRuke(w http.ResponseWriter, r *http.Request) {
+	u, err := userFromRequest(r)
+	if err != nil {
+		jsonError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ruke": zapisi.rukeZaVezbanje(u.ID)})
+}
+
+// POST /api/vezbanje {"ruka": 12, "mesto": 1, "od_stiha": 0}
+func handleVezbanje(w http.ResponseWriter, r *http.Request) {
+	u, err := userFromRequest(r)
+	if err != nil {
+		jsonError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	var z zahtevVezbe
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16384)).Decode(&z); err != nil {
+		jsonError(w, r, http.StatusBadRequest, tr("neispravan_zahtev"))
+		return
+	}
+	var zapis ZapisRuke
+	switch {
+	case z.Ruka != nil:
+		// sačuvane ruke vidi samo onaj ko ih je igrao
+		zr, ok := zapisi.ruka(*z.Ruka)
+		if !ok || mesto(zr.Igraci, u.ID) < 0 || zr.Podela == nil {
+			jsonError(w, r, http.StatusNotFound, tr("nema_ruke"))
+			return
+		}
+		zapis = zr
+	case z.Zapis != nil:
+		zapis = *z.Zapis
+	default:
+		jsonError(w, r, http.StatusBadRequest, tr("vezba_bez_ruke"))
+		return
+	}
+	if zapis.Pravila == "" {
+		zapis.Pravila = konfig.Pravila
+	}
+	if z.Mesto < 0 || z.Mesto > 2 {
+		jsonError(w, r, http.StatusBadRequest, tr("vezba_mesto"))
+		return
+	}
+	if err := proveriZapis(zapis, z.OdStiha); err != nil {
+		jsonError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if gasiSe.Load() {
+		jsonError(w, r, http.StatusServiceUnavailable, errGasiSe)
+		return
+	}
+	room := novaVezba(u, zapis, z.Mesto, z.OdStiha)
+	slog.Info("vežbanje", "soba", room.id, "korisnik", u.ID, "mesto", z.Mesto, "od_stiha", z.OdStiha)
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"soba":  room.id,
+		"mesto": z.Mesto,
+		"ws":    "/ws?soba=" + room.id,
+	})
+}
+
+// proveriZapis proverava da li zapis može da se odigra od zadatog štiha
+func proveriZapis(z ZapisRuke, odStiha int) error {
+	rules, err := preferans.RulesZa(z.Pravila)
+	if err != nil {
+		return nepoznataPravila(z.Pravila)
+	}
+	ostatak, _ := preferans.Ukloni(preferans.Deck, z.Podela...)
+	if len(z.Podela) != len(preferans.Deck) || len(ostatak) != 0 {
+		return tr("vezba_podela")
+	}
+	if z.Prvi < 0 || z.Prvi > 2 {
+		return tr("vezba_prvi")
+	}
+	if odStiha == 0 {
+		return nil
+	}
+	if odStiha < 1 || odStiha > 10 {
+		return tr("vezba_od_stiha_granice")
+	}
+	// brojčane ponude pa igra, betl i sans
+	if z.Deklarant < 0 || z.Deklarant > 2 || z.Ponuda < preferans.MinBroj || z.Ponuda > preferans.Sans {
+		return tr("vezba_bez_ugovora")
+	}
+	// kontra do najviše što pravila dozvoljavaju; prate samo branioci, a bar jedan mora
+	if z.Kontra < 0 || (z.Kontra > 0 && !rules.SmeKontru(z.Kontra-1)) {
+		return tr("vezba_kontra", z.Kontra)
+	}
+	if z.Pratili[z.Deklarant] || !slices.Contains(z.Pratili[:], true) {
+		return tr("vezba_pratili")
+	}
+	o, err := pocetakIgre(z)
+	if err != nil {
+		return err
+	}
+	n := 3 * (odStiha - 1)
+	if len(z.Bacene) < n {
+		return tr("vezba_malo_stihova", odStiha-1)
+	}
+	for _, c := range z.Bacene[:n] {
+		if _, err := o.Baci(o.NaPotezu, c); err != nil {
+			return tr("vezba_stih", o.Odigrano+1, porukaGreske(err))
+		}
+	}
+	return nil
+}
+
+// pocetakIgre pravi odigravanje iz zapisa: ruke posle škarta, adut i prvi na potezu
+func pocetakIgre(z ZapisRuke) (*preferans.Odigravanje, error) {
+	var ruke [3][]string
+	for i := range ruke {
+		ruke[i] = slices.Clone(z.Podela[i*10 : (i+1)*10])
+	}
+	d := z.Deklarant
+	if preferans.SaTalonom(z.Ponuda) {
+		ruka, _ := preferans.Ukloni(append(ruke[d], z.Podela[30:]...), z.Skart...)
+		if len(ruka) != 10 {
+			return nil, tr("vezba_skart")
+		}
+		ruke[d] = ruka
+	}
+	adut := preferans.AdutIzStila(z.Adut)
+	if !preferans.BiraAdut(z.Ponuda) {
+		adut = 0
+	} else if adut == 0 {
+		return nil, tr("vezba_adut", z.Adut)
+	}
+	return preferans.NovoOdigravanje(ruke, adut, d), nil
+}
+
+// novaVezba pravi sobu sa dva bota; igrač preuzima svoje mesto preko /ws?soba=
+func novaVezba(u *User, z ZapisRuke, mesto, odStiha int) *Room {
+	rules, _ := preferans.RulesZa(z.Pravila)
+	mu.Lock()
+	defer mu.Unlock()
+	// korisnik vežba za jednim stolom, nova vežba zatvara staru
+	for id, room := range rooms {
+		if room.vezbanje != nil && room.vezbanje.korisnik == u.ID {
+			delete(rooms, id)
+		}
+	}
+	brojVezbi++
+	id := fmt.Sprintf("vezba%d", brojVezbi)
+	room := &Room{
+		id:       id,
+		rules:    rules,
+		maxRefe:  rules.MaxRefe,
+		trening:  true,
+		vezbanje: &vezbanje{korisnik: u.ID, zapis: z, mesto: mesto, odStiha: odStiha},
+	}
+	for i := range 3 {
+		p := &Player{id: i, bot: true, povezan: true, name: fmt.Sprintf("Bot %d", i+1)}
+		if i == mesto {
+			p = &Player{id: i, zamena: u.ID}
+		}
+		p.uSobu(id)
+		room.players = append(room.players, p)
+	}
+	room.match = newMatch(room)
+	rooms[id] = room
+	return room
+}
+
+// ukloniSobu briše sobu iz spiska, ako je u međuvremenu nije zamenila druga
+func ukloniSobu(room *Room) {
+	mu.Lock()
+	defer mu.Unlock()
+	if rooms[room.id] == room {
+		delete(rooms, room.id)
+	}
+}
+
+// pocniVezbanje deli karte kad igrač sedne; zove se sa zaključanim r.mu
+func (r *Room) pocniVezbanje() {
+	v := r.vezbanje
+	v.pocelo = true
+	poruka := tr("vezba_od_licitacije")
+	if v.odStiha > 0 {
+		poruka = tr("vezba_od_stiha", v.odStiha)
+	}
+	r.broadcast(map[string]any{
+		"type":    "info",
+		"message": poruka,
+	})
+	if v.odStiha > 0 {
+		// od štiha nema licitacije, pa ni njenih poziva
+		v.podeljeno = true
+		r.startIndex = v.zapis.Prvi
+		rasporediKarte(r, slices.Clone(v.zapis.Podela))
+		r.premotaj()
+	} else {
+		dealCards(r)
+	}
+	if v.podeljeno {
+		r.igrajBotove()
+	}
+}
+
+// podeli zadaje špil iz zapisa za dealCards. Vežba je jedna podela, pa novo
+// deljenje (svi su rekli pas ili igra nije važila) završava vežbu.
+func (v *vezbanje) podeli(r *Room) bool {
+	if v.podeljeno {
+		r.zavrsiVezbanje(nil)
+		return false
+	}
+	v.podeljeno = true
+	r.spil = slices.Clone(v.zapis.Podela)
+	r.startIndex = v.zapis.Prvi
+	return true
+}
+
+// premotaj preskače licitaciju i tiho odigrava štihove pre zadatog onako kako
+// su odigrani u zapisu, pa tek onda javlja igru; zapis je proveren pri
+// pravljenju sobe
+func (r *Room) premotaj() {
+	v := r.vezbanje
+	z := v.zapis
+	o, _ := pocetakIgre(z)
+	for _, p := range r.players {
+		p.cards = slices.Clone(o.Ruke[p.id])
+		p.kontrirao = z.Kontrirali[p.id]
+		p.prihvatio = z.Pratili[p.id]
+	}
+	r.highestBidder, r.highestBid, r.adut = r.igrac(z.Deklarant), z.Ponuda, z.Adut
+	r.ponude = slices.Clone(z.Licitacija)
+	r.kontraStatus, r.kontraActive = z.Kontra, z.Kontra > 0
+	if preferans.SaTalonom(z.Ponuda) {
+		r.talonUzet, r.skart = true, slices.Clone(z.Skart)
+	}
+	pripremiIgru(r)
+	for _, c := range z.Bacene[:3*(v.odStiha-1)] {
+		p := r.igrac(r.igra.NaPotezu)
+		r.igra.Baci(p.id, c)
+		p.cards, _ = preferans.Ukloni(p.cards, c)
+		r.odigrane[c] = true
+		r.bacene = append(r.bacene, c)
+	}
+	v.zapamtiPocetak(r)
+	objaviIgru(r)
+}
+
+// zapamtiPocetak čuva kopiju odigravanja od kog igra vežba
+func (v *vezbanje) zapamtiPocetak(r *Room) {
+	v.pocetak, v.deklarant, v.najvise = r.igra.Kopija(), r.highestBidder.id, r.highestBid != preferans.Betl
+}
+
+// zavrsiVezbanje poredi ishod vežbe sa originalom i sa najboljom igrom sa
+// otvorenim kartama; o je nil kad ruka nije odigrana
+func (r *Room) zavrsiVezbanje(o *preferans.Obracun) {
+	v := r.vezbanje
+	v.gotovo = true
+	z := v.zapis
+	msg := map[string]any{
+		"type":  "kraj_vezbe",
+		"mesto": v.mesto,
+	}
+	delovi := []*Poruka{}
+	if o == nil {
+		delovi = append(delovi, tr("vezba_nije_odigrana"))
+	} else {
+		msg["vezba"] = ishodRuke(o.Deklarant, o.Ponuda, o.Stihovi, o.Prosao)
+		delovi = append(delovi, tr("vezba_ishod", opisIshoda(o.Deklarant, o.Ponuda, o.Stihovi, o.Prosao)))
+	}
+	if z.Ponuda != 0 {
+		msg["original"] = ishodRuke(z.Deklarant, z.Ponuda, z.Stihovi, z.Prosao)
+		delovi = append(delovi, tr("vezba_original", opisIshoda(z.Deklarant, z.Ponuda, z.Stihovi, z.Prosao)))
+	}
+	if o == nil || v.pocetak == nil {
+		msg["message"] = spoj(delovi...)
+		r.broadcast(msg)
+		return
+	}
+	// najbolja igra od početka vežbe je puna pretraga, pa ide van brave
+	var naj int
+	ponuda := o.Ponuda
+	r.uPozadini(func() {
+		naj = v.pocetak.Stihovi[v.deklarant] + v.pocetak.SigurniStihovi(v.deklarant, v.najvise)
+	}, func() {
+		msg["najbolje"] = map[string]any{
+			"deklarant": v.deklarant,
+			"ponuda":    ponuda,
+			"stihova":   naj,
+		}
+		delovi = append(delovi, tr("vezba_najbolje", v.deklarant, naj))
+		msg["message"] = spoj(delovi...)
+		r.broadcast(msg)
+	})
+}
+
+func ishodRuke(deklarant, ponuda int, stihovi [3]int, prosao bool) map[string]any {
+	return map[string]any{
+		"deklarant": deklarant,
+		"ponuda":    ponuda,
+		"stihovi":   stihovi,
+		"prosao":    prosao,
+	}
+}
+
+func opisIshoda(deklarant, ponuda int, stihovi [3]int, prosao bool) *Poruka {
+	kod := "ishod_pada"
+	if prosao {
+		kod = "ishod_prolazi"
+	}
+	return tr(kod, deklarant, ugovor(ponuda), stihovi[deklarant])
+}
+
+// ==== Botovi ====
+
+// igrajBotove pušta botove da donesu sve odluke koje su na njima; zove se sa
+// zaključanim r.mu
+func (r *Room) igrajBotove() {
+	if r.vezbanje == nil {
+		return
+	}
+	for range maxPotezaBotova {
+		if r.vezbanje.gotovo {
+			return
+		}
+		p, m := r.potezBota()
+		if p == nil {
+			return
+		}
+		r.izvrsi(p, m)
+	}
+	r.log().Warn("botovi nisu završili poteze", "poteza", maxPotezaBotova)
+}
+
+// potezBota nalazi bota od koga se traži odluka i poruku kojom je donosi
+func (r *Room) potezBota() (*Player, map[string]any) {
+	for _, p := range r.players {
+		if !p.bot {
+			continue
+		}
+		if r.zahtev != nil {
+			// bot prihvata zahtev, server ga ionako proverava
+			if p != r.highestBidder && !r.zahtev.prihvatili[p.id] {
+				return p, map[string]any{"type": "claim_odgovor", "prihvatam": true}
+			}
+			continue
+		}
+		faza, s, ok := r.savetZa(p)
+		if !ok {
+			continue
+		}
+		switch faza {
+		case "licitacija":
+			// bot bira iz istih ponuda koje dobija igrač; igru bez talona ne najavljuje
+			ponude, _ := r.moguce(p)["ponude"].([]int)
+			ponude = slices.DeleteFunc(slices.Clone(ponude), func(v int) bool { return v > preferans.MaxBroj })
+			s = preferans.SavetLicitacija(p.cards, ponude)
+			if v, err := strconv.Atoi(s.Predlog); err == nil {
+				return p, map[string]any{"type": "bid", "value": float64(v)}
+			}
+			return p, map[string]any{"type": "pass"}
+		case "potvrda":
+			// adut ide uz potvrdu samo kad je ponuđen
+			m := map[string]any{"type": "potvrdi_igru"}
+			if len(r.adutiPotvrde()) > 0 {
+				m["value"] = s.Predlog
+			}
+			return p, m
+		case "adut":
+			return p, map[string]any{"type": "stil_odabran", "stil": s.Predlog}
+		case "skart":
+			if len(s.Karte) == 2 {
+				return p, map[string]any{"type": "odbaci_karte", "karte": []any{s.Karte[0], s.Karte[1]}}
+			}
+		case "pracenje":
+			return p, map[string]any{"type": "prati", "prati": s.Predlog == "prati"}
+		case "kontra":
+			return p, map[string]any{"type": "kontra_odgovor", "kontra": s.Predlog == "kontra"}
+		case "igra":
+			return p, map[string]any{"type": "baci_kartu", "card": s.Predlog}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"maps"
+	"slices"
+)
+
+// ==== Vidljivost ====
+// Sve što soba šalje igraču ili gledaocu prolazi kroz vidljivo. Svaka karta u
+// poljima poruke mora biti karta koju primalac sme da vidi u trenutnoj fazi:
+// svoja ruka, karte bačene na sto, talon kad ga je deklarant uzeo (svima samo
+// ako pravila otkrivaju talon), škart samo deklarantu i ruka deklaranta koji je
+// uz zahtev otvorio karte. Karta koja ne sme da prođe izbacuje se iz poruke i
+// beleži kao greška, da bi propust u handleru završio u dnevniku, a ne kod
+// protivnika.
+
+// poljaSaKartama su polja u kojima soba šalje karte
+var poljaSaKartama = []string{"cards", "talon", "card", "skart", "stih", "otvorene", "legalne"}
+
+// vidljiveKarte su karte koje p sme da vidi; zove se sa zaključanim r.mu
+func (r *Room) vidljiveKarte(p *Player) map[string]bool {
+	v := map[string]bool{}
+	for c := range r.odigrane {
+		v[c] = true
+	}
+	deklarant := !p.gledalac && p == r.highestBidder
+	if r.talonUzet && (r.rules.TalonOtkriven || deklarant) {
+		for _, c := range r.talon {
+			v[c] = true
+		}
+	}
+	if deklarant {
+		for _, c := range r.skart {
+			v[c] = true
+		}
+	}
+	if r.otkrivena != nil {
+		for _, c := range r.otkrivena.cards {
+			v[c] = true
+		}
+	}
+	if !p.gledalac {
+		for _, c := range p.cards {
+			v[c] = true
+		}
+	}
+	return v
+}
+
+// vidljivo vraća poruku kakvu p sme da primi. Poruka se ne menja na mestu jer
+// broadcast istu mapu šalje svima; kopija se pravi samo kad nešto mora da se izbaci.
+func (r *Room) vidljivo(p *Player, msg map[string]any) map[string]any {
+	var vidljive map[string]bool
+	out, kopija := msg, false
+	for _, polje := range poljaSaKartama {
+		v, ok := msg[polje]
+		if !ok {
+			continue
+		}
+		if vidljive == nil {
+			vidljive = r.vidljiveKarte(p)
+		}
+		var skrivene []string
+		switch k := v.(type) {
+		case string:
+			if !vidljive[k] {
+				skrivene = []string{k}
+				v = ""
+			}
+		case []string:
+			dozvoljene := slices.DeleteFunc(slices.Clone(k), func(c string) bool { return !vidljive[c] })
+			if len(dozvoljene) < len(k) {
+				for _, c := range k {
+					if !vidljive[c] {
+						skrivene = append(skrivene, c)
+					}
+				}
+				v = dozvoljene
+			}
+		}
+		if skrivene == nil {
+			continue
+		}
+		if !kopija {
+			out, kopija = maps.Clone(msg), true
+		}
+		out[polje] = v
+		metrika.greske.dodaj("hidden_card")
+		p.log().Error("poruka je sadržala skrivene karte", "tip", msg["type"], "polje", polje, "karte", len(skrivene))
+	}
+	return out
+}
+
+// posalji šalje poruku jednom igraču ili gledaocu ove sobe; zove se sa zaključanim r.mu
+func (r *Room) posalji(p *Player, msg map[string]any) {
+	p.conn.WriteJSON(r.vidljivo(p, msg))
+}
+
+// greska javlja igraču zašto njegova poruka nije prihvaćena; zove se sa zaključanim r.mu
+func (r *Room) greska(p *Player, poruka any) {
+	r.posalji(p, map[string]any{
+		"type":    "error",
+		"message": poruka,
+	})
+}
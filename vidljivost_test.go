@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"multiplayer-game/preferans"
+)
+
+// Testovi prolaze celu ruku kroz handleMessage i proveravaju svaku poruku koja
+// izađe iz servera. Šta je kome skriveno računa se ovde, iz stanja stola i toka
+// igre, a ne preko vidljiveKarte, da greška u sloju vidljivosti ne bi sakrila
+// samu sebe. Koren modula ima više main fajlova, pa se testovi pokreću sa
+// spiskom fajlova:
+//
+//	go test main5poslednje.go match.go ... vidljivost.go vidljivost_test.go
+
+// testSto je sto sa tri igrača i gledaocem čije veze samo pune red
+type testSto struct {
+	t        *testing.T
+	r        *Room
+	gledalac *Player
+	// talonViden[i]: igrač na mestu i sme da zna talon ove podele
+	talonViden    [3]bool
+	talonOtkriven bool    // talon je otkriven svima za stolom, i gledaocima
+	otvorena      *Player // deklarant koji je uz zahtev otvorio karte
+	poruka        int     // brojač poruka, za čitljive greške
+}
+
+func testVeza() *veza {
+	return &veza{red: make(chan []byte, velicinaReda), zatvorena: make(chan struct{})}
+}
+
+func noviTestSto(t *testing.T, pravila string) *testSto {
+	t.Helper()
+	postaviSkladiste(t.TempDir())
+	zapisi = &statsStore{}
+	rules := preferans.Presets[pravila]
+	id := "vidljivost-" + pravila
+	r := &Room{id: id, rules: rules, maxRefe: rules.MaxRefe}
+	for i := range 3 {
+		p := &Player{id: i, name: fmt.Sprintf("igrac%d", i), conn: testVeza(), povezan: true}
+		p.uSobu(id)
+		r.players = append(r.players, p)
+	}
+	g := &Player{name: "gledalac", conn: testVeza(), gledalac: true, povezan: true}
+	g.uSobu(id)
+	r.gledaoci = []*Player{g}
+	r.match = newMatch(r)
+
+	mu.Lock()
+	rooms[id] = r
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		delete(rooms, id)
+		mu.Unlock()
+	})
+
+	s := &testSto{t: t, r: r, gledalac: g}
+	s.akcija(func() {
+		r.mu.Lock()
+		dealCards(r)
+		r.mu.Unlock()
+	})
+	return s
+}
+
+// skrivene su karte koje primalac u ovom trenutku ne sme da zna
+func (s *testSto) skrivene(primalac *Player) map[string]bool {
+	h := map[string]bool{}
+	for _, p := range s.r.players {
+		if p == primalac {
+			continue
+		}
+		for _, c := range p.cards {
+			h[c] = true
+		}
+	}
+	vidiTalon := s.talonOtkriven || (!primalac.gledalac && s.talonViden[primalac.id])
+	for _, c := range s.r.talon {
+		// talon koji je primalac video ostaje poznat i kad je u ruci deklaranta
+		h[c] = !vidiTalon
+	}
+	if primalac.gledalac || primalac != s.r.highestBidder {
+		for _, c := range s.r.skart {
+			// otkriven talon ne otkriva škart, ali ni škart ne skriva otkriven talon
+			h[c] = !vidiTalon || !slices.Contains(s.r.talon, c)
+		}
+	}
+	if s.otvorena != nil {
+		for _, c := range s.otvorena.cards {
+			h[c] = false
+		}
+	}
+	for c := range s.r.odigrane {
+		// bačena karta je javna i kad je bila u talonu
+		h[c] = false
+	}
+	return h
+}
+
+func (s *testSto) primaoci() []*Player {
+	return append(slices.Clone(s.r.players), s.gledalac)
+}
+
+// akcija izvršava korak igre i proverava sve poruke koje je on proizveo. Karta
+// je skrivena ako je primalac nije smeo da zna ni pre ni posle koraka; bačene
+// karte su javne. Tako nova podela posle poslednjeg štiha ne daje lažne greške,
+// a karta koja je procurela bilo kada se i dalje vidi. Korak ne sme ni da
+// pokuša da pošalje skrivenu kartu: vidljivo je poslednja odbrana, pa svaka
+// karta koju je moralo da izbaci znači grešku u handleru.
+func (s *testSto) akcija(korak func(), bacene ...string) {
+	s.t.Helper()
+	pre := map[*Player]map[string]bool{}
+	for _, p := range s.primaoci() {
+		pre[p] = s.skrivene(p)
+	}
+	izbacene := metrika.greske.vrednost("hidden_card")
+	korak()
+	if n := metrika.greske.vrednost("hidden_card") - izbacene; n > 0 {
+		// vidljivo je skrivene karte izbacilo, ali handler ih je poslao
+		s.t.Errorf("korak posle poruke %d je poslao skrivene karte %d puta", s.poruka, n)
+	}
+	for _, p := range s.primaoci() {
+		posle := s.skrivene(p)
+		for _, data := range s.isprazni(p) {
+			s.poruka++
+			for c := range pre[p] {
+				if !slices.Contains(bacene, c) && posle[c] && bytes.Contains(data, []byte(c)) {
+					s.t.Errorf("poruka %d za %s sadrži skrivenu kartu %s: %s", s.poruka, p.name, c, data)
+				}
+			}
+		}
+	}
+}
+
+func (s *testSto) isprazni(p *Player) [][]byte {
+	var out [][]byte
+	for {
+		select {
+		case data := <-p.conn.red:
+			out = append(out, data)
+		default:
+			return out
+		}
+	}
+}
+
+func (s *testSto) posalji(p *Player, msg map[string]any) {
+	s.t.Helper()
+	data, err := json.Marshal(msg)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	var bacene []string
+	if c, ok := msg["card"].(string); ok {
+		bacene = append(bacene, c)
+	}
+	if msg["type"] == "claim_odgovor" && msg["prihvatam"] == true && s.r.zahtev != nil && len(s.r.zahtev.prihvatili) == 1 {
+		// prihvaćen zahtev završava ruku, pa su sve karte iz ruku javne
+		for _, pl := range s.r.players {
+			bacene = append(bacene, pl.cards...)
+		}
+	}
+	s.akcija(func() { handleMessage(p, data) }, bacene...)
+}
+
+// odigrajRuku vodi jednu podelu do obračuna: prvi na redu licitira ponuda,
+// ostali kažu pas, a kontre nema. Pre potvrde oba protivnika pokušavaju da
+// izazovu talon porukama koje smeju da pošalju samo deklarantu. Sa zahtevi
+// deklarant posle trećeg štiha otvara karte zahtevom koji se odbija, a posle
+// šestog završava ruku prihvaćenim zahtevom.
+func (s *testSto) odigrajRuku(ponuda int, zahtevi bool) {
+	s.t.Helper()
+	r := s.r
+	s.talonViden, s.talonOtkriven, s.otvorena = [3]bool{}, false, nil
+	deklarant := r.players[r.licitacija.NaPotezu]
+	s.posalji(deklarant, map[string]any{"type": "bid", "value": ponuda})
+	var protivnici []*Player
+	for _, p := range r.players {
+		if p != deklarant {
+			protivnici = append(protivnici, p)
+		}
+	}
+	for range protivnici {
+		// pas se kaže redom, kao i svaka ponuda
+		s.posalji(r.players[r.licitacija.NaPotezu], map[string]any{"type": "pass"})
+	}
+	if r.highestBidder != deklarant || r.faza != fazaPotvrda {
+		s.t.Fatalf("licitacija nije završena kod deklaranta (%d)", deklarant.id)
+	}
+
+	for _, p := range protivnici {
+		s.posalji(p, map[string]any{"type": "stil_odabran", "stil": "♠"})
+		s.posalji(p, map[string]any{"type": "odbaci_karte", "karte": p.cards[:2]})
+		if len(p.cards) != 10 {
+			s.t.Fatalf("%s je uzeo talon bez licitacije: %v", p.name, p.cards)
+		}
+	}
+
+	saTalonom := preferans.SaTalonom(ponuda)
+	if saTalonom {
+		// deklarant vidi talon od biraj_stil, ostali samo ako ga pravila otkrivaju
+		s.talonViden[deklarant.id] = true
+		s.talonOtkriven = r.rules.TalonOtkriven
+	}
+	potvrda := map[string]any{"type": "potvrdi_igru"}
+	if !saTalonom {
+		// adut uz potvrdu se šalje samo u igri bez talona
+		potvrda["value"] = "♠"
+	}
+	s.posalji(deklarant, potvrda)
+	if saTalonom {
+		s.posalji(deklarant, map[string]any{"type": "stil_odabran", "stil": "♠"})
+		// drugi stil_odabran ne sme ponovo da doda talon u ruku
+		s.posalji(deklarant, map[string]any{"type": "stil_odabran", "stil": "♠"})
+		if len(deklarant.cards) != 12 {
+			s.t.Fatalf("deklarant posle talona ima %d karata", len(deklarant.cards))
+		}
+		s.posalji(deklarant, map[string]any{"type": "odbaci_karte", "karte": deklarant.cards[10:]})
+		if len(deklarant.cards) != 10 || len(r.skart) != 2 {
+			s.t.Fatalf("škart nije prihvaćen: ruka %v, škart %v", deklarant.cards, r.skart)
+		}
+	}
+	for i := 1; i <= 2; i++ {
+		// protivnici redom kažu da prate
+		s.posalji(r.players[(deklarant.id+i)%3], map[string]any{"type": "prati", "prati": true})
+	}
+	for i := 1; i <= 2; i++ {
+		// i redom odbijaju kontru
+		s.posalji(r.players[(deklarant.id+i)%3], map[string]any{"type": "kontra_odgovor", "kontra": false})
+	}
+	if r.igra == nil {
+		s.t.Fatal("igra nije počela")
+	}
+
+	podela := r.match.podele
+	for r.match.podele == podela {
+		if zahtevi && r.igra.Odigrano >= 3 && s.otvorena == nil {
+			// zahtev protivnika se ne prihvata; zahtev deklaranta otvara samo njegove karte
+			s.posalji(protivnici[0], map[string]any{"type": "claim", "stihova": 0})
+			s.otvorena = deklarant
+			s.posalji(deklarant, map[string]any{"type": "claim", "stihova": 0})
+			s.posalji(protivnici[0], map[string]any{"type": "claim_odgovor", "prihvatam": false})
+		}
+		if zahtevi && r.igra.Odigrano >= 6 {
+			s.posalji(deklarant, map[string]any{"type": "claim", "stihova": 0})
+			for _, p := range protivnici {
+				s.posalji(p, map[string]any{"type": "claim_odgovor", "prihvatam": true})
+			}
+			if r.match.podele == podela {
+				s.t.Fatal("prihvaćen zahtev nije završio ruku")
+			}
+			break
+		}
+		p := r.igrac(r.igra.NaPotezu)
+		odigrana := false
+		for _, c := range slices.Clone(p.cards) {
+			s.posalji(p, map[string]any{"type": "baci_kartu", "card": c})
+			// posle poslednjeg štiha već je podeljena nova ruka
+			if r.match.podele != podela || !slices.Contains(p.cards, c) {
+				odigrana = true
+				break
+			}
+		}
+		if !odigrana {
+			s.t.Fatalf("%s nema kartu koju sme da baci: %v", p.name, p.cards)
+		}
+	}
+}
+
+func TestBezSkrivenihKarata(t *testing.T) {
+	for _, pravila := range []string{"standard", "klub"} {
+		for _, ponuda := range []int{3, 4, 6, 7} {
+			t.Run(fmt.Sprintf("%s/%d", pravila, ponuda), func(t *testing.T) {
+				s := noviTestSto(t, pravila)
+				s.odigrajRuku(ponuda, false)
+				s.odigrajRuku(ponuda, true)
+				if s.poruka == 0 {
+					t.Fatal("nije proverena nijedna poruka")
+				}
+			})
+		}
+	}
+}
+
+// Deklarant mora da dobije talon u biraj_stil: sloj vidljivosti ne sme da
+// skriva ono što igrač treba da vidi.
+func TestDeklarantVidiTalon(t *testing.T) {
+	s := noviTestSto(t, "klub")
+	r := s.r
+	deklarant := r.players[r.licitacija.NaPotezu]
+	s.posalji(deklarant, map[string]any{"type": "bid", "value": 3})
+	for range 2 {
+		s.posalji(r.players[r.licitacija.NaPotezu], map[string]any{"type": "pass"})
+	}
+	talon := slices.Clone(r.talon)
+	data, _ := json.Marshal(map[string]any{"type": "potvrdi_igru"})
+	handleMessage(deklarant, data)
+
+	var biraj struct {
+		Cards []string `json:"cards"`
+	}
+	for _, m := range s.isprazni(deklarant) {
+		if strings.Contains(string(m), `"biraj_stil"`) {
+			json.Unmarshal(m, &biraj)
+		}
+	}
+	if !slices.Equal(biraj.Cards, talon) {
+		t.Fatalf("biraj_stil: %v, talon %v", biraj.Cards, talon)
+	}
+	for _, p := range r.players {
+		if p == deklarant {
+			continue
+		}
+		for _, m := range s.isprazni(p) {
+			for _, c := range talon {
+				if bytes.Contains(m, []byte(c)) {
+					t.Errorf("%s vidi zatvoren talon: %s", p.name, m)
+				}
+			}
+		}
+	}
+}
+
+// Poruka sa tuđom kartom gubi tu kartu, a mapa koju broadcast deli ostaje ista.
+func TestVidljivoIzbacujeTudjeKarte(t *testing.T) {
+	s := noviTestSto(t, "standard")
+	r := s.r
+	ja, drugi := r.players[0], r.players[1]
+	tudja := drugi.cards[0]
+	msg := map[string]any{
+		"type":  "your_cards",
+		"cards": []string{ja.cards[0], tudja},
+		"card":  tudja,
+		"talon": slices.Clone(r.talon),
+	}
+
+	r.mu.Lock()
+	out := r.vidljivo(ja, msg)
+	zaGledaoca := r.vidljivo(s.gledalac, msg)
+	zaDrugog := r.vidljivo(drugi, msg)
+	r.mu.Unlock()
+
+	if got := out["cards"].([]string); !slices.Equal(got, []string{ja.cards[0]}) {
+		t.Errorf("cards: %v", got)
+	}
+	if out["card"] != "" || len(out["talon"].([]string)) != 0 {
+		t.Errorf("card %v, talon %v", out["card"], out["talon"])
+	}
+	if len(zaGledaoca["cards"].([]string)) != 0 {
+		t.Errorf("gledalac vidi %v", zaGledaoca["cards"])
+	}
+	if got := zaDrugog["cards"].([]string); !slices.Equal(got, []string{tudja}) || zaDrugog["card"] != tudja {
+		t.Errorf("vlasnik karte: %v %v", got, zaDrugog["card"])
+	}
+	if len(msg["cards"].([]string)) != 2 || msg["card"] != tudja {
+		t.Errorf("originalna poruka je izmenjena: %v", msg)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import "multiplayer-game/preferans"
+
+// ==== Zahtev (claim) ====
+// Deklarant u igri može da kaže koliko će još štihova uzeti i pritom otvara
+// karte. Ako jedan protivnik odbije, igra se dalje, a karte ostaju otvorene.
+// Ako oba prihvate, server jednom pretragom sa otvorenim kartama, van brave
+// stola, računa koliko deklarant sigurno uzima protiv bilo koje odbrane i kako
+// bi se ostali štihovi podelili najboljom igrom, i time završava ruku. Pošten
+// zahtev se upisuje onako kako je postavljen, a nepošten po najboljoj igri i
+// ostaje zabeležen za administratora (GET /admin/claims).
+
+type zahtev struct {
+	stihova    int
+	prihvatili map[int]bool
+}
+
+// ZapisZahteva se upisuje uz ruku koja je završena prihvaćenim zahtevom
+type ZapisZahteva struct {
+	Stihova  int  `json:"stihova"`  // koliko je deklarant tvrdio da uzima od preostalih
+	Moguce   int  `json:"moguce"`   // koliko sigurno uzima protiv najbolje odbrane
+	Odigrano int  `json:"odigrano"` // štihova završenih pre zahteva
+	Posten   bool `json:"posten"`
+}
+
+// zahtevaj postavlja zahtev deklaranta; zove se sa zaključanim r.mu
+func (r *Room) zahtevaj(p *Player, stihova int) {
+	if p != r.highestBidder {
+		return
+	}
+	if r.zahtev != nil {
+		r.greska(p, tr("zahtev_ceka"))
+		return
+	}
+	preostalo := 10 - r.igra.Odigrano
+	if stihova < 0 || stihova > preostalo {
+		r.greska(p, tr("zahtev_granice", preostalo))
+		return
+	}
+	r.zahtev = &zahtev{stihova: stihova, prihvatili: map[int]bool{}}
+	r.otkrivena = p
+	r.broadcast(map[string]any{
+		"type":    "claim",
+		"message": tr("zahtev", p.ime(), stihova, preostalo),
+		"player":  p.id,
+		"stihova": stihova,
+		"cards":   p.cards,
+	})
+	for _, pl := range r.players {
+		if pl != p {
+			r.pozovi(pl, map[string]any{
+				"type":    "claim_prompt",
+				"message": tr("zahtev_pitanje"),
+			})
+		}
+	}
+}
+
+// odgovorNaZahtev beleži odgovor protivnika; zove se sa zaključanim r.mu
+func (r *Room) odgovorNaZahtev(p *Player, prihvata bool) {
+	if r.zahtev == nil || p == r.highestBidder || r.zahtev.prihvatili[p.id] {
+		return
+	}
+	if !prihvata {
+		r.zahtev = nil
+		r.broadcast(map[string]any{
+			"type":    "info",
+			"message": tr("zahtev_odbijen", p.ime()),
+		})
+		javiPotez(r)
+		return
+	}
+	r.zahtev.prihvatili[p.id] = true
+	r.broadcast(map[string]any{
+		"type":    "info",
+		"message": tr("zahtev_prihvacen", p.ime()),
+	})
+	if len(r.zahtev.prihvatili) == 2 {
+		r.resiZahtev()
+	}
+}
+
+// resiZahtev proverava prihvaćen zahtev, deli preostale štihove i boduje
+// ruku. Pretraga ide van brave; dok traje zahtev ostaje postavljen, pa se
+// karte ne bacaju. Zove se sa zaključanim r.mu.
+func (r *Room) resiZahtev() {
+	z := r.zahtev
+	d := r.highestBidder
+	// u betlu deklarant tvrdi da neće uzeti više od zadatog
+	najvise := r.highestBid != preferans.Betl
+	igra := r.igra.Kopija()
+	var raspodela [3]int
+	r.uPozadini(func() {
+		raspodela = igra.Raspodela(d.id, najvise)
+	}, func() {
+		if r.zahtev != z {
+			// ruka je u međuvremenu prekinuta
+			return
+		}
+		r.zahtev = nil
+		r.primeniZahtev(z, raspodela)
+	})
+}
+
+// primeniZahtev upisuje ishod zahteva po raspodeli najbolje igre i završava ruku
+func (r *Room) primeniZahtev(z *zahtev, raspodela [3]int) {
+	d := r.highestBidder
+	najvise := r.highestBid != preferans.Betl
+	moguce := raspodela[d.id]
+	posten := moguce >= z.stihova
+	if !najvise {
+		posten = moguce <= z.stihova
+	}
+	r.zapisZahteva = &ZapisZahteva{Stihova: z.stihova, Moguce: moguce, Odigrano: r.igra.Odigrano, Posten: posten}
+	dobija := moguce
+	if posten {
+		dobija = z.stihova
+	} else {
+		metrika.greske.dodaj("unfair_claim")
+		d.log().Warn("nepošten zahtev", "stihova", z.stihova, "moguce", moguce)
+	}
+	r.podeliOstatak(d.id, dobija, raspodela)
+
+	poruka := tr("zahtev_vazi", d.ime(), z.stihova)
+	if !posten {
+		poruka = tr("zahtev_ne_vazi", moguce, z.stihova)
+	}
+	r.broadcast(map[string]any{
+		"type":    "claim_ishod",
+		"message": poruka,
+		"posten":  posten,
+		"stihova": z.stihova,
+		"moguce":  moguce,
+		"stihovi": r.igra.Stihovi,
+	})
+	zavrsiRuku(r)
+}
+
+// podeliOstatak upisuje štihove koje bi svako uzeo najboljom igrom. Kad
+// deklarant zahtevom uzima manje nego što sigurno nosi, višak dobijaju
+// branioci koji prate, naizmenično od levog; kad u betlu uzima više, razliku
+// daje branilac kome je ostalo više štihova.
+func (r *Room) podeliOstatak(d, dobija int, raspodela [3]int) {
+	levi, desni := (d+1)%3, (d+2)%3
+	var pratioci []int
+	for _, i := range []int{levi, desni} {
+		if r.igrac(i).prihvatio {
+			pratioci = append(pratioci, i)
+		}
+	}
+	for i := 0; raspodela[d] > dobija; i++ {
+		raspodela[d]--
+		raspodela[pratioci[i%len(pratioci)]]++
+	}
+	for raspodela[d] < dobija {
+		od := levi
+		if raspodela[desni] > raspodela[levi] {
+			od = desni
+		}
+		raspodela[od]--
+		raspodela[d]++
+	}
+	for i, n := range raspodela {
+		r.igra.Stihovi[i] += n
+	}
+}
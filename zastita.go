@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ==== Zaštita /ws ====
+// Svaka veza i svaka IP adresa imaju svoju kofu žetona za poruke; nalet sme da
+// bude dvostruko veći od dozvoljene brzine. Poruka bez žetona se odbacuje, a
+// veza koja napravi konfig.MaxPrekrsaja prekršaja (odbačene ili neispravne
+// poruke) zatvara se kodom 1008 (Policy Violation). IP adresa se uzima iz
+// RemoteAddr, pa iza proxy-ja ograničenja važe za ceo proxy.
+
+// kofa je token bucket; nije bezbedna za više gorutina
+type kofa struct {
+	zetoni    float64
+	brzina    float64 // žetona u sekundi
+	kapacitet float64
+	poslednje time.Time
+}
+
+func novaKofa(brzina int) *kofa {
+	k := &kofa{brzina: float64(brzina), kapacitet: 2 * float64(brzina), poslednje: time.Now()}
+	k.zetoni = k.kapacitet
+	return k
+}
+
+func (k *kofa) uzmi() bool {
+	sada := time.Now()
+	k.zetoni = min(k.kapacitet, k.zetoni+sada.Sub(k.poslednje).Seconds()*k.brzina)
+	k.poslednje = sada
+	if k.zetoni < 1 {
+		return false
+	}
+	k.zetoni--
+	return true
+}
+
+// puna javlja da li se kofa do sada već napunila do vrha
+func (k *kofa) puna() bool {
+	return k.zetoni+time.Since(k.poslednje).Seconds()*k.brzina >= k.kapacitet
+}
+
+type ipStanje struct {
+	veze int
+	kofa *kofa
+}
+
+var (
+	ipMu     sync.Mutex
+	poAdresi = map[string]*ipStanje{}
+)
+
+func adresaKlijenta(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// prijaviVezu broji vezu sa adrese; false znači da je adresa već na granici
+func prijaviVezu(ip string) bool {
+	ipMu.Lock()
+	defer ipMu.Unlock()
+	s := poAdresi[ip]
+	if s == nil {
+		s = &ipStanje{kofa: novaKofa(konfig.IPPorukaUSekundi)}
+		poAdresi[ip] = s
+	}
+	if s.veze >= konfig.VezaPoIP {
+		return false
+	}
+	s.veze++
+	return true
+}
+
+func odjaviVezu(ip string) {
+	ipMu.Lock()
+	defer ipMu.Unlock()
+	if s := poAdresi[ip]; s != nil {
+		s.veze--
+		if s.veze <= 0 {
+			delete(poAdresi, ip)
+		}
+	}
+}
+
+func ipDozvoljava(ip string) bool {
+	ipMu.Lock()
+	defer ipMu.Unlock()
+	s := poAdresi[ip]
+	return s == nil || s.kofa.uzmi()
+}
+
+// cuvar proverava poruke jedne veze pre obrade; koristi ga samo petlja čitanja
+type cuvar struct {
+	ip        string
+	kofa      *kofa
+	prekrsaji int
+}
+
+func noviCuvar(ip string) *cuvar {
+	return &cuvar{ip: ip, kofa: novaKofa(konfig.PorukaUSekundi)}
+}
+
+// propusti javlja da li poruku treba obraditi. Kad veza pređe granicu
+// prekršaja, zatvara je i vraća prekini=true.
+func (c *cuvar) propusti(conn *veza, msg []byte) (obradi, prekini bool) {
+	var razlog *Poruka
+	switch {
+	case !c.kofa.uzmi() || !ipDozvoljava(c.ip):
+		metrika.greske.dodaj("rate_limited")
+		razlog = tr("previse_poruka")
+	case !ispravnaPoruka(msg):
+		metrika.greske.dodaj("invalid_message")
+		razlog = tr("neispravna_poruka")
+	default:
+		return true, false
+	}
+	c.prekrsaji++
+	if c.prekrsaji >= konfig.MaxPrekrsaja {
+		metrika.greske.dodaj("policy_disconnect")
+		conn.zatvoriSa(websocket.ClosePolicyViolation, "previše neispravnih ili prebrzih poruka")
+		return false, true
+	}
+	conn.WriteJSON(map[string]any{
+		"type":    "error",
+		"message": razlog,
+	})
+	return false, false
+}
+
+// ispravnaPoruka je JSON objekat sa tekstualnim poljem type
+func ispravnaPoruka(msg []byte) bool {
+	var m struct {
+		Type *string `json:"type"`
+	}
+	return json.Unmarshal(msg, &m) == nil && m.Type != nil
+}